@@ -1,9 +1,6 @@
 package web
 
 import (
-	"encoding/base64"
-	"encoding/json"
-	"errors"
 	"github.com/elancom/go-util/crypto"
 	"github.com/elancom/go-util/lang"
 	"github.com/elancom/go-util/param"
@@ -20,10 +17,14 @@ type UserPrincipal struct {
 	Secret    string `json:"secret"`    // 通信秘钥(16位)
 	Random    string `json:"random"`    // 随机字符串
 	Timestamp int64  `json:"timestamp"` // 时间戳(毫秒)
+
+	Roles []string `json:"roles,omitempty"` // 角色
+	Perms []string `json:"perms,omitempty"` // 权限
 }
 
-func MakeToken(id int64, username string, secret string, aesKey []byte) (string, error) {
-	principal := UserPrincipal{
+// NewUserPrincipal 创建用户凭证
+func NewUserPrincipal(id int64, username string, secret string) *UserPrincipal {
+	return &UserPrincipal{
 		Id:        id,
 		Username:  username,
 		Key:       crypto.NewId32(),
@@ -31,50 +32,23 @@ func MakeToken(id int64, username string, secret string, aesKey []byte) (string,
 		Random:    rand.RandomStr(32),
 		Timestamp: time.Now().UnixMilli(),
 	}
-	marshal, err := json.Marshal(principal)
-	if err != nil {
-		return "", err
-	}
-
-	encrypt, err := crypto.AesEcbEncrypt(marshal, aesKey)
-	if err != nil {
-		return "", errors.New("encrypt(0)")
-	}
-
-	enStr := base64.StdEncoding.EncodeToString(encrypt)
-	if err != nil {
-		return "", errors.New("encrypt(es)")
-	}
-
-	return enStr, nil
 }
 
-func GetUserPrincipal(token string) (*UserPrincipal, error) {
-	if str.IsBlank(token) {
-		return nil, errors.New("token err")
-	}
-
-	tokenBytes, err := base64.StdEncoding.DecodeString(token)
-	if err != nil {
-		return nil, errors.New("token err(DC)")
-	}
-
-	decrypt, err := crypto.AesEcbDecrypt(tokenBytes, []byte("1234567890123456"))
-	if err != nil {
-		return nil, errors.New("token err(0)")
-	}
+func MakeToken(id int64, username string, secret string, aesKey []byte) (string, error) {
+	return MakeTokenWith(NewGcmTokenCodec(aesKey), id, username, secret)
+}
 
-	principal := new(UserPrincipal)
-	err = json.Unmarshal(decrypt, principal)
-	if err != nil {
-		return nil, errors.New("token err(1)")
-	}
+// MakeTokenWith 使用指定编解码生成令牌
+func MakeTokenWith(codec TokenCodec, id int64, username string, secret string) (string, error) {
+	return codec.Encode(NewUserPrincipal(id, username, secret))
+}
 
-	if principal.Id == 0 || str.IsBlank(principal.Username) || str.IsBlank(principal.Key) || principal.Timestamp == 0 {
-		return nil, errors.New("token err(2)")
+// GetUserPrincipal 令牌解析(未指定编解码时使用默认秘钥, 不接受旧版令牌)
+func GetUserPrincipal(token string, codec ...TokenCodec) (*UserPrincipal, error) {
+	if len(codec) > 0 && codec[0] != nil {
+		return codec[0].Decode(token)
 	}
-
-	return principal, nil
+	return NewGcmTokenCodec(defaultTokenKey).Decode(token)
 }
 
 func parseUserPrincipal(c *fiber.Ctx) (*UserPrincipal, error) {
@@ -85,12 +59,34 @@ func parseUserPrincipal(c *fiber.Ctx) (*UserPrincipal, error) {
 		return nil, lang.NewErr("token err(B)")
 	}
 
-	principal, err := GetUserPrincipal(token)
+	s := serverOf(c)
+	if s == nil {
+		principal, err := GetUserPrincipal(token)
+		if err != nil {
+			return nil, lang.NewErr(err.Error())
+		}
+		return principal, nil
+	}
+
+	principal, err := s.GetUserPrincipal(token)
+	if err == TokenExpired {
+		return nil, err
+	}
 	if err != nil {
-		return nil, c.JSON(lang.NewErr(err.Error()))
+		return nil, lang.NewErr(err.Error())
+	}
+
+	// 有效期
+	if err = s.checkTokenTime(principal, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// 吊销
+	if err = s.checkRevoked(principal); err != nil {
+		return nil, err
 	}
 
-	return principal, err
+	return principal, nil
 }
 
 type HandleWithUser func(principal *UserPrincipal) error
@@ -100,7 +96,7 @@ func ResolveUser(c *fiber.Ctx) (*UserPrincipal, error) {
 	if principal, ok := c.Context().Value("principal").(*UserPrincipal); ok {
 		return principal, nil
 	}
-	return nil, lang.NewErr("principal error")
+	return nil, newBindErr("principal", sourceUser, lang.NewErr("principal error"))
 }
 
 // UseUser 注入用户
@@ -115,14 +111,12 @@ func UseUserParam(handle HandleP2[*UserPrincipal, string], name string) fiber.Ha
 
 // UseUserParam2 UseUser 注入用户
 func UseUserParam2(handle HandleP3[*UserPrincipal, string, string], name1 string, name2 string) fiber.Handler {
-	creator := newParamFuncCreator()
-	return Bind3(handle, ResolveUser, creator(name1), creator(name2))
+	return Bind3(handle, ResolveUser, ResolveParam(name1), ResolveParam(name2))
 }
 
 // UseUserParam3 UseUser 注入用户
 func UseUserParam3(handle HandleP4[*UserPrincipal, string, string, string], name1 string, name2 string, name3 string) fiber.Handler {
-	creator := newParamFuncCreator()
-	return Binds(handle, ResolveUser, creator(name1), creator(name2), creator(name3))
+	return Binds(handle, ResolveUser, ResolveParam(name1), ResolveParam(name2), ResolveParam(name3))
 }
 
 // UseUserBody UseUser 注入用户
@@ -142,8 +136,7 @@ func UseUserPageParam(handle HandleP3[*UserPrincipal, *lang.Page, string], name
 
 // UseUserPageParam2 UseUser 注入用户
 func UseUserPageParam2(handle HandleP4[*UserPrincipal, *lang.Page, string, string], name1 string, name2 string) fiber.Handler {
-	creator := newParamFuncCreator()
-	return Binds(handle, ResolveUser, ResolvePage, creator(name1), creator(name2))
+	return Binds(handle, ResolveUser, ResolvePage, ResolveParam(name1), ResolveParam(name2))
 }
 
 // UseUserPageBody UseUser 注入用户
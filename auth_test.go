@@ -0,0 +1,244 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/elancom/go-util/lang"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 发送请求并解析消息
+func testMsg(t *testing.T, server *Server, request *http.Request) *lang.Msg {
+	resp, err := server.App.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := new(lang.Msg)
+	if err = json.Unmarshal(body, msg); err != nil {
+		t.Fatal(string(body), err)
+	}
+	return msg
+}
+
+func TestTokenKey(t *testing.T) {
+	server := NewServer(Config{
+		AuthEnable: true,
+		TokenKey:   []byte("abcdefghijklmnop"),
+	})
+	server.Init()
+	server.App.Get("/me", UseUser(func(p *UserPrincipal) error {
+		return lang.NewOk(p.Username)
+	}))
+
+	token, err := server.MakeToken(1, "tom", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetUserPrincipal(token); err == nil {
+		t.Fatal("default key should not decode")
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, "/me", nil)
+	request.Header.Set("x-token", token)
+	if msg := testMsg(t, server, request); !msg.IsOk() || msg.Data != "tom" {
+		t.Fatal(msg)
+	}
+
+	request, _ = http.NewRequest(http.MethodGet, "/me", nil)
+	request.Header.Set("x-token", testToken)
+	if msg := testMsg(t, server, request); msg.IsOk() {
+		t.Fatal(msg)
+	}
+}
+
+func TestTokenCodec(t *testing.T) {
+	codec := NewAesTokenCodec([]byte("abcdefghijklmnop"))
+	token, err := MakeTokenWith(codec, 1, "tom", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err := GetUserPrincipal(token, codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Id != 1 || principal.Secret != testSecret {
+		t.Fatal(principal)
+	}
+}
+
+func TestTokenTTL(t *testing.T) {
+	server := NewServer(Config{
+		AuthEnable:   true,
+		TokenTTL:     time.Hour,
+		TokenSkew:    time.Second,
+		TokenRefresh: 10 * time.Minute,
+	})
+	server.Init()
+	server.App.Get("/me", Use(func() error { return lang.NewOk() }))
+
+	request := func(age time.Duration) *http.Request {
+		principal := NewUserPrincipal(1, "tom", testSecret)
+		principal.Timestamp = time.Now().Add(-age).UnixMilli()
+		token, err := server.codec.Encode(principal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		request.Header.Set("x-token", token)
+		return request
+	}
+
+	if msg := testMsg(t, server, request(2*time.Hour)); msg.Code != CodeTokenExpired {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request(-time.Minute)); msg.Code != CodeTokenExpired {
+		t.Fatal(msg)
+	}
+
+	resp, err := server.App.Test(request(55 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := resp.Header.Get("x-token-refresh")
+	if fresh == "" {
+		t.Fatal("token not refreshed")
+	}
+	if principal, err := server.GetUserPrincipal(fresh); err != nil || principal.Id != 1 {
+		t.Fatal(principal, err)
+	}
+
+	resp, _ = server.App.Test(request(time.Minute))
+	if resp.Header.Get("x-token-refresh") != "" {
+		t.Fatal("token refreshed too early")
+	}
+}
+
+func TestLegacyToken(t *testing.T) {
+	legacy, err := MakeTokenWith(NewAesTokenCodec(defaultTokenKey), 1, "tom", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := MakeToken(1, "tom", testSecret, defaultTokenKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codec := newDefaultTokenCodec(newSingleKeyring(defaultTokenKey), time.Now().Add(time.Hour))
+	for _, tk := range []string{legacy, token, testToken} {
+		if _, err = codec.Decode(tk); err != nil {
+			t.Fatal(tk, err)
+		}
+	}
+
+	// 默认不接受旧版令牌
+	if _, err = newDefaultTokenCodec(newSingleKeyring(defaultTokenKey), time.Time{}).Decode(legacy); err == nil {
+		t.Fatal("legacy token accepted by default")
+	}
+	if _, err = GetUserPrincipal(legacy); err == nil {
+		t.Fatal("legacy token accepted by default")
+	}
+
+	codec = newDefaultTokenCodec(newSingleKeyring(defaultTokenKey), time.Now().Add(-time.Hour))
+	if _, err = codec.Decode(legacy); err == nil {
+		t.Fatal("legacy token accepted after migration")
+	}
+	if _, err = codec.Decode(token); err != nil {
+		t.Fatal(err)
+	}
+
+	// 篡改
+	tampered := []byte(token)
+	tampered[len(tampered)-2] ^= 1
+	if _, err = codec.Decode(string(tampered)); err == nil {
+		t.Fatal("tampered token accepted")
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	server := NewServer(Config{
+		AuthEnable:      true,
+		TokenTTL:        time.Hour,
+		RevocationStore: NewMemoryRevocationStore(),
+		IgnoreUrls:      []string{"/opt"},
+	})
+	server.Init()
+	server.App.Get("/me", Use(func() error { return lang.NewOk() }))
+	server.App.Get("/opt", UseOptUser(func(user *UserPrincipal) error { return lang.NewOk() }))
+	server.App.Get("/logout", func(c *fiber.Ctx) error {
+		if err := Logout(c); err != nil {
+			return err
+		}
+		return lang.NewOk()
+	})
+
+	request := func(path string, token string) *http.Request {
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set("x-token", token)
+		return request
+	}
+
+	t1, _ := server.MakeToken(1, "tom", testSecret)
+	t2, _ := server.MakeToken(1, "tom", testSecret)
+	if msg := testMsg(t, server, request("/logout", t1)); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/me", t1)); msg.Code != CodeTokenRevoked {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/opt", t1)); msg.Code != CodeTokenRevoked {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/me", t2)); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+
+	// 全部注销
+	if err := server.RevokeUser(1, time.Now().Add(time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if msg := testMsg(t, server, request("/me", t2)); msg.Code != CodeTokenRevoked {
+		t.Fatal(msg)
+	}
+}
+
+type testPerms map[int64][]string
+
+func (p testPerms) Roles(id int64) ([]string, error) { return nil, nil }
+func (p testPerms) Perms(id int64) ([]string, error) { return p[id], nil }
+
+func TestRequireRole(t *testing.T) {
+	server := NewServer(Config{AuthEnable: true, PermissionResolver: testPerms{2: {"order:*"}}})
+	server.Init()
+	ok := Use(func() error { return lang.NewOk() })
+	server.App.Get("/admin", RequireRole("admin"), ok)
+	server.App.Get("/order", RequirePerm("order:write"), ok)
+
+	request := func(path string, id int64, roles ...string) *http.Request {
+		principal := NewUserPrincipal(id, "tom", testSecret)
+		principal.Roles = roles
+		token, _ := server.EncodeToken(principal)
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set("x-token", token)
+		return request
+	}
+
+	if msg := testMsg(t, server, request("/admin", 1, "user")); msg.Msg != lang.NotAuthorized.Error() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/admin", 1, "admin")); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/order", 1)); msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/order", 2)); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+}
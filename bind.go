@@ -1,13 +1,16 @@
 package web
 
 import (
+	"fmt"
 	"github.com/elancom/go-util/collection"
 	"github.com/elancom/go-util/lang"
 	"github.com/elancom/go-util/number"
 	"github.com/elancom/go-util/param"
 	"github.com/elancom/go-util/str"
 	"github.com/gofiber/fiber/v2"
+	"log"
 	"net/http"
+	"reflect"
 )
 
 // 参数绑定
@@ -29,22 +32,21 @@ type HandleWithInt[T int | int64] func(i T) error
 // Resolver 参数解析器
 type Resolver[T any] func(c *fiber.Ctx) (T, error)
 
-// Nil参数解析
-var none Resolver[any] = func(c *fiber.Ctx) (any, error) { return nil, nil }
-
-func ResolveInt[T int | int64](name string) func(c *fiber.Ctx) (T, error) {
-	return func(c *fiber.Ctx) (T, error) {
-		p := ""
-		if c.Method() == http.MethodPost {
-			p = c.Params(name)
-		} else {
-			p = c.Query(name)
-		}
-		if str.IsBlank(p) {
-			return T(0), lang.NewErr(name + " missing")
-		}
-		return number.ToInt[T](p)
+// 请求内容是否在请求体
+// POST/PUT/PATCH: 请求体; DELETE: 有请求体时为请求体; 其他(GET/HEAD等): 查询串
+func hasBody(c *fiber.Ctx) bool {
+	switch c.Method() {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	case http.MethodDelete:
+		return len(c.Body()) > 0
 	}
+	return false
+}
+
+// ResolveInt 整数参数
+func ResolveInt[T int | int64](name string) Resolver[T] {
+	return resolveValue(name, func(s string) (T, error) { return number.ToInt[T](s) })
 }
 
 // UseInt 注入参数
@@ -64,10 +66,7 @@ func UseId64(handler HandleWithInt[int64]) fiber.Handler {
 
 // ResolvePathVar 路由参数解析
 func ResolvePathVar(c *fiber.Ctx) (*param.Params, error) {
-	if c.Method() == http.MethodPost {
-		return param.NewParams(c.AllParams()), nil
-	}
-	return param.NewParams(map[string]string{}), nil
+	return param.NewParams(c.AllParams()), nil
 }
 
 // UsePathVar  注入路由参数
@@ -75,15 +74,16 @@ func UsePathVar(handler HandleWithParams) fiber.Handler {
 	return Bind1(func(p1 *param.Params) error { return handler(p1) }, ResolvePathVar)
 }
 
-// ResolveForm 表单参数解析
+// ResolveForm 表单参数解析(x-www-form-urlencoded/multipart)
 func ResolveForm(c *fiber.Ctx) (*param.Params, error) {
-	if c.Method() == http.MethodPost {
-		args := c.Request().PostArgs()
-		m := make(map[string]string, args.Len())
-		args.VisitAll(func(key, value []byte) { m[string(key)] = string(value) })
-		return param.NewParams(m), nil
+	params, err := ResolveRequestParams(c)
+	if err != nil {
+		return nil, err
+	}
+	if params.bodyKind != bodyForm && params.bodyKind != bodyMultipart {
+		return param.NewParams(map[string]string{}), nil
 	}
-	return param.NewParams(map[string]string{}), nil
+	return param.NewParams(params.BodyMap()), nil
 }
 
 // UseForm 注入表单参数
@@ -91,27 +91,24 @@ func UseForm(handler HandleWithForm) fiber.Handler {
 	return Bind1(func(p1 *param.Params) error { return handler(p1) }, ResolveForm)
 }
 
+// ResolveParams 请求参数解析(路由参数 > 请求体 > 查询串, 见RequestParams)
 func ResolveParams(c *fiber.Ctx) (*param.Params, error) {
-	m, err := make(map[string]string), error(nil)
-	switch c.Method() {
-	case http.MethodPost:
-		// todo mb a bug
-		err = c.BodyParser(&m)
-	case http.MethodGet:
-		args := c.Request().URI().QueryArgs()
-		args.VisitAll(func(key, value []byte) { m[string(key)] = string(value) })
-	}
+	params, err := ResolveRequestParams(c)
 	if err != nil {
 		return nil, err
 	}
-	return param.NewParams(m), nil
+	return param.NewParams(params.Map()), nil
 }
 
+// ResolveParam 请求参数, 缺失或name为空时为空串
 func ResolveParam(name string) Resolver[string] {
 	return func(c *fiber.Ctx) (string, error) {
-		params, err := ResolveParams(c)
+		if name == "" {
+			return "", nil
+		}
+		params, err := ResolveRequestParams(c)
 		if err != nil {
-			return "", err
+			return "", toBindErr(err, 0).withName(name)
 		}
 		return params.Get(name), nil
 	}
@@ -128,22 +125,21 @@ func UseParams(handle HandleP1[*param.Params]) fiber.Handler {
 }
 
 // ResolveBody 解析body
-// 支持post(json)/post_form(form_data)/get
+// 支持post/put/patch/delete(json/form_data), get/delete(查询串)
+// 解析后按 validate 标签及 Validator 校验
 func ResolveBody[T any](gen Supplier[T]) Resolver[T] {
 	return func(c *fiber.Ctx) (T, error) {
 		dist := gen()
-		err := error(nil)
-		switch c.Method() {
-		case http.MethodPost:
-			err = c.BodyParser(dist)
-		case http.MethodGet:
-			err = c.QueryParser(dist)
-		default:
-			err = lang.NewErr("not support use body")
-		}
+		params, err := requestParams(c)
 		if err != nil {
 			return dist, err
 		}
+		if err = params.decode(dist); err != nil {
+			return dist, newBindErr("", sourceOf(c), err)
+		}
+		if err = Validate(dist); err != nil {
+			return dist, err
+		}
 		return dist, nil
 	}
 }
@@ -159,21 +155,14 @@ func Use(handle Handle) fiber.Handler {
 }
 
 func ResolvePage(c *fiber.Ctx) (*lang.Page, error) {
-	page, pv, prows := new(lang.Page), "", ""
-	switch c.Method() {
-	case http.MethodGet:
-		gen := func(_ int, it string) string { return c.Query(it) }
-		pv = collection.FindMapS2s([]string{"page", "current"}, gen)
-		prows = collection.FindMapS2s([]string{"rows", "pageSize"}, gen)
-	case http.MethodPost:
-		m := make(map[string]string)
-		err := c.BodyParser(m)
-		if err == nil {
-			gen := func(_ int, it string) string { return m[it] }
-			pv = collection.FindMapS2s([]string{"page", "current"}, gen)
-			prows = collection.FindMapS2s([]string{"rows", "pageSize"}, gen)
-		}
+	params, err := ResolveRequestParams(c)
+	if err != nil {
+		return nil, err
 	}
+	gen := func(_ int, it string) string { return params.Get(it) }
+	page := new(lang.Page)
+	pv := collection.FindMapS2s([]string{"page", "current"}, gen)
+	prows := collection.FindMapS2s([]string{"rows", "pageSize"}, gen)
 	if str.IsNotBlank(pv) {
 		if i := number.ToIntL(pv, 0); i > 0 {
 			page.SetPage(i)
@@ -229,8 +218,7 @@ func UsePageParam2(handle HandleP3[*lang.Page, string, string], name1 string, na
 }
 
 func UsePageParam3(handle HandleP4[*lang.Page, string, string, string], name1, name2, name3 string) fiber.Handler {
-	creator := newParamFuncCreator()
-	return Binds(handle, ResolvePage, creator(name1), creator(name2), creator(name3))
+	return Binds(handle, ResolvePage, ResolveParam(name1), ResolveParam(name2), ResolveParam(name3))
 }
 
 func UsePageCountParam(handle HandleP3[*lang.Page, bool, string], name string) fiber.Handler {
@@ -254,34 +242,80 @@ func ResolveFlag(c *fiber.Ctx) (*lang.Flag, error) {
 	return f, nil
 }
 
-// 参数解析器构建器
-func newParamFuncCreator() func(name string) Resolver[string] {
-	f := func(name string) Resolver[string] {
-		return func(c *fiber.Ctx) (string, error) {
-			if str.IsBlank(name) {
-				return "", nil
-			}
-			// 防重复解析参数
-			params, _ := c.Context().Value("__params_iE2_iA").(*param.Params)
-			if params == nil {
-				p, err := ResolveParams(c)
-				if err != nil {
-					return "", err
+// Args 已解析参数(按解析器顺序)
+type Args []any
+
+// Get 取第i个参数, 类型不符时panic(解析器与处理器参数不一致, BindN 中转为内部错误)
+func Get[T any](args Args, i int) T {
+	if args[i] == nil {
+		var zero T
+		return zero
+	}
+	v, ok := args[i].(T)
+	if !ok {
+		panic(&argTypeError{fmt.Sprintf("web: args[%d] type %T, expect %v", i, args[i], reflect.TypeOf((*T)(nil)).Elem())})
+	}
+	return v
+}
+
+// R 类型参数解析器转任意类型解析器, 用于 BindN
+func R[T any](r Resolver[T]) Resolver[any] {
+	return func(c *fiber.Ctx) (any, error) { return r(c) }
+}
+
+// BindN 绑定任意个参数
+//
+//	BindN(func(a Args) error {
+//		return handle(Get[*UserPrincipal](a, 0), Get[int64](a, 1), Get[string](a, 2))
+//	}, R(ResolveUser), R(ResolveInt[int64]("id")), R(ResolveParam("name")))
+func BindN(fn func(args Args) error, resolvers ...Resolver[any]) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		args := make(Args, len(resolvers))
+		for i, r := range resolvers {
+			p, err := r(c)
+			if err != nil {
+				removeTempUploads(c, true)
+				// 校验错误原样返回字段明细, 认证错误原样返回(401)
+				if isValidateErr(err) || isAuthErr(err) {
+					return err
 				}
-				params = p
-				c.Context().SetUserValue("__params_iE2_iA", p)
+				return toBindErr(err, i+1)
 			}
-			return params.Get(name), nil
+			args[i] = p
 		}
+		return callArgs(fn, args)
 	}
-	return f
 }
 
-// Bind0 绑定1个参数
+// 参数类型不符(解析器与处理器不一致)
+type argTypeError struct {
+	msg string
+}
+
+func (e *argTypeError) Error() string {
+	return e.msg
+}
+
+// 调用处理器, 参数类型不符时返回错误(内部错误), 不中断服务
+func callArgs(fn func(args Args) error, args Args) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*argTypeError)
+			if !ok {
+				panic(r)
+			}
+			log.Println("[参数类型错误]", e)
+			err = e
+		}
+	}()
+	return fn(args)
+}
+
+// Bind0 绑定0个参数
 func Bind0(
 	fn Handle,
 ) fiber.Handler {
-	return Binds(func(p1 any, n2 any, n3 any, n4 any) error { return fn() }, none, none, none, none)
+	return BindN(func(a Args) error { return fn() })
 }
 
 // Bind1 绑定1个参数
@@ -289,7 +323,7 @@ func Bind1[T1 any](
 	fn HandleP1[T1],
 	r1 Resolver[T1],
 ) fiber.Handler {
-	return Binds(func(p1 T1, n2 any, n3 any, n4 any) error { return fn(p1) }, r1, none, none, none)
+	return BindN(func(a Args) error { return fn(Get[T1](a, 0)) }, R(r1))
 }
 
 // Bind2 绑定2个参数
@@ -297,7 +331,7 @@ func Bind2[T1 any, T2 any](
 	fn HandleP2[T1, T2],
 	r1 Resolver[T1], r2 Resolver[T2],
 ) fiber.Handler {
-	return Binds(func(p1 T1, p2 T2, n3 any, n4 any) error { return fn(p1, p2) }, r1, r2, none, none)
+	return BindN(func(a Args) error { return fn(Get[T1](a, 0), Get[T2](a, 1)) }, R(r1), R(r2))
 }
 
 // Bind3 绑定3个参数
@@ -305,7 +339,9 @@ func Bind3[T1 any, T2 any, T3 any](
 	fn HandleP3[T1, T2, T3],
 	r1 Resolver[T1], r2 Resolver[T2], r3 Resolver[T3],
 ) fiber.Handler {
-	return Binds(func(p1 T1, p2 T2, p3 T3, n4 any) error { return fn(p1, p2, p3) }, r1, r2, r3, none)
+	return BindN(func(a Args) error {
+		return fn(Get[T1](a, 0), Get[T2](a, 1), Get[T3](a, 2))
+	}, R(r1), R(r2), R(r3))
 }
 
 // Binds 绑定4个参数
@@ -313,31 +349,7 @@ func Binds[T1 any, T2 any, T3 any, T4 any](
 	fn HandleP4[T1, T2, T3, T4],
 	r1 Resolver[T1], r2 Resolver[T2], r3 Resolver[T3], r4 Resolver[T4],
 ) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		// 参数1
-		p1, err := r1(c)
-		if err != nil {
-			return lang.NewErr("p1 resolve err")
-		}
-
-		// 参数2
-		p2, err := r2(c)
-		if err != nil {
-			return lang.NewErr("p2 resolve err")
-		}
-
-		// 参数3
-		p3, err := r3(c)
-		if err != nil {
-			return lang.NewErr("p3 resolve err")
-		}
-
-		// 参数4
-		p4, err := r4(c)
-		if err != nil {
-			return lang.NewErr("p4 resolve err")
-		}
-
-		return fn(p1, p2, p3, p4)
-	}
+	return BindN(func(a Args) error {
+		return fn(Get[T1](a, 0), Get[T2](a, 1), Get[T3](a, 2), Get[T4](a, 3))
+	}, R(r1), R(r2), R(r3), R(r4))
 }
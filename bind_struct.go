@@ -0,0 +1,256 @@
+package web
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 结构体绑定
+//
+//	type OrderReq struct {
+//		Id     int64          `path:"id"`
+//		Q      string         `query:"q"`
+//		Tenant string         `header:"x-tenant"`
+//		Name   string         `param:"name"` // 同ResolveParam(路由参数 > 请求体 > 查询串)
+//		Body   *OrderBody     `body:""`
+//		User   *UserPrincipal `user:""`
+//	}
+//	app.Post("/order/:id", web.Bind(func(req *OrderReq) error { ... }))
+
+// 绑定来源
+const (
+	sourcePath   = "path"
+	sourceQuery  = "query"
+	sourceHeader = "header"
+	sourceParam  = "param"
+	sourceBody   = "body"
+	sourceUser   = "user"
+	sourceFile   = "file" // 上传文件(不支持标签绑定)
+)
+
+var bindSources = []string{sourcePath, sourceQuery, sourceHeader, sourceParam, sourceBody, sourceUser}
+
+var userPrincipalType = reflect.TypeOf((*UserPrincipal)(nil))
+
+// 字段绑定
+type fieldBinding struct {
+	index  []int
+	source string
+	name   string
+	typ    reflect.Type
+}
+
+// 类型绑定缓存 reflect.Type => []fieldBinding
+var structBindings sync.Map
+
+// Bind 结构体绑定, 按字段标签从请求中取值, 取值后按 validate 标签校验
+func Bind[T any](handle HandleP1[*T]) fiber.Handler {
+	return Bind1(handle, ResolveStruct[T]())
+}
+
+// ResolveStruct 结构体参数解析
+func ResolveStruct[T any]() Resolver[*T] {
+	return func(c *fiber.Ctx) (*T, error) {
+		dist := new(T)
+		v := reflect.ValueOf(dist).Elem()
+		if v.Kind() != reflect.Struct {
+			return nil, errors.New("bind target must be struct")
+		}
+		fields, err := structBindingOf(v.Type())
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			if err = bindField(c, v.FieldByIndex(f.index), f); err != nil {
+				return nil, err
+			}
+		}
+		if err = Validate(dist); err != nil {
+			return nil, err
+		}
+		return dist, nil
+	}
+}
+
+func structBindingOf(t reflect.Type) ([]fieldBinding, error) {
+	if fields, ok := structBindings.Load(t); ok {
+		return fields.([]fieldBinding), nil
+	}
+	fields, err := parseStructBinding(t, nil)
+	if err != nil {
+		return nil, err
+	}
+	structBindings.Store(t, fields)
+	return fields, nil
+}
+
+func parseStructBinding(t reflect.Type, parent []int) ([]fieldBinding, error) {
+	fields := make([]fieldBinding, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+
+		source, name, tagged := "", "", false
+		for _, src := range bindSources {
+			if tag, ok := sf.Tag.Lookup(src); ok {
+				source, name, tagged = src, tag, true
+				break
+			}
+		}
+
+		// 嵌入结构体展开
+		if !tagged {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				sub, err := parseStructBinding(sf.Type, index)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, sub...)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			return nil, errors.New("bind field not exported: " + sf.Name)
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if source == sourceUser && sf.Type != userPrincipalType {
+			return nil, errors.New("user field must be *UserPrincipal: " + sf.Name)
+		}
+		fields = append(fields, fieldBinding{index: index, source: source, name: name, typ: sf.Type})
+	}
+	return fields, nil
+}
+
+func bindField(c *fiber.Ctx, fv reflect.Value, f fieldBinding) error {
+	switch f.source {
+	case sourceUser:
+		user, err := ResolveUser(c)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(user))
+		return nil
+	case sourceBody:
+		t := f.typ
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		body, err := ResolveBody[any](func() any { return reflect.New(t).Interface() })(c)
+		if err != nil {
+			return err
+		}
+		bv := reflect.ValueOf(body)
+		if f.typ.Kind() != reflect.Pointer {
+			bv = bv.Elem()
+		}
+		fv.Set(bv)
+		return nil
+	}
+
+	var values []string
+	switch f.source {
+	case sourceHeader:
+		values = []string{c.Get(f.name)}
+	default:
+		params, err := requestParams(c)
+		if err == nil && f.source == sourceParam {
+			err = params.bodyErr
+		}
+		if err != nil {
+			return toBindErr(err, 0).withName(f.name)
+		}
+		switch f.source {
+		case sourcePath:
+			values = []string{params.Path(f.name)}
+		case sourceQuery:
+			values = params.queryValues(f.name)
+		case sourceParam:
+			values, _ = params.Lookup(f.name)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	// 切片取全部值, 其他取第一个
+	s := values[0]
+	if t := f.typ; t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Slice {
+		s = strings.Join(values, ",")
+	}
+	if s == "" {
+		return nil
+	}
+	if err := setValue(fv, s); err != nil {
+		return newBindErr(f.name, f.source, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 字符串转换赋值(基本类型/指针/切片, 切片以逗号分隔)
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		nv := reflect.New(v.Type().Elem())
+		if err := setValue(nv.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(nv)
+		return nil
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		parts := strings.Split(s, ",")
+		sv := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setValue(sv.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	default:
+		return errors.New("bind type not support: " + v.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"fmt"
+	"github.com/elancom/go-util/lang"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newBindServer() *Server {
+	server := NewServer(Config{})
+	server.Init()
+	return server
+}
+
+func TestBindN(t *testing.T) {
+	server := newBindServer()
+	server.App.Get("/n", BindN(func(a Args) error {
+		s := []string{Get[string](a, 0), Get[string](a, 1), Get[string](a, 2), Get[string](a, 3), Get[string](a, 4)}
+		return lang.NewOk(strings.Join(s, ",") + ":" + lang.Ifs(Get[bool](a, 5), "count", "list"))
+	}, R(ResolveParam("a")), R(ResolveParam("b")), R(ResolveParam("c")), R(ResolveParam("d")), R(ResolveParam("e")), R(ResolveIsCount)))
+	server.App.Get("/err", BindN(func(a Args) error {
+		return lang.NewOk()
+	}, R(ResolveParam("a")), R(ResolveInt[int]("id"))))
+
+	request, _ := http.NewRequest(http.MethodGet, "/n?a=1&b=2&c=3&d=4&e=5", nil)
+	if msg := testMsg(t, server, request); msg.Data != "1,2,3,4,5:list" {
+		t.Fatal(msg)
+	}
+	request, _ = http.NewRequest(http.MethodGet, "/err?id=x", nil)
+	msg := testMsg(t, server, request)
+	data, _ := msg.Data.(map[string]any)
+	if msg.Code != CodeBind || msg.Msg != "id resolve err" ||
+		data["index"] != float64(2) || data["name"] != "id" || data["source"] != "query" || data["cause"] != nil {
+		t.Fatal(msg)
+	}
+}
+
+func TestGet(t *testing.T) {
+	args := Args{"a", nil}
+	if Get[string](args, 0) != "a" || Get[*UserPrincipal](args, 1) != nil {
+		t.Fatal(args)
+	}
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "args[0] type string, expect int") {
+			t.Fatal(r)
+		}
+	}()
+	Get[int](args, 0)
+}
+
+func TestBindNArgType(t *testing.T) {
+	server := newBindServer()
+	server.App.Get("/n", BindN(func(a Args) error {
+		return lang.NewOk(Get[int](a, 0))
+	}, R(ResolveParam("name"))))
+	request, _ := http.NewRequest(http.MethodGet, "/n?name=tom", nil)
+	if msg := testMsg(t, server, request); msg.IsOk() || msg.Msg != "InternalServerError" {
+		t.Fatal(msg)
+	}
+}
+
+func TestBindErrDetail(t *testing.T) {
+	for detail, want := range map[BindDetail]string{BindDetailNone: "", BindDetailCause: "path"} {
+		server := NewServer(Config{BindErrDetail: detail})
+		server.Init()
+		server.App.Post("/order/:id", Bind(func(req *testOrderReq) error { return lang.NewOk() }))
+
+		request, _ := http.NewRequest(http.MethodPost, "/order/x", strings.NewReader(`{}`))
+		request.Header.Set("Content-Type", "application/json")
+		msg := testMsg(t, server, request)
+		if msg.Code != CodeBind {
+			t.Fatal(msg)
+		}
+		data, _ := msg.Data.(map[string]any)
+		if want == "" && (msg.Msg != "param err" || msg.Data != nil) {
+			t.Fatal(msg)
+		}
+		if want != "" && (data["source"] != want || data["name"] != "id" || data["cause"] == nil) {
+			t.Fatal(msg)
+		}
+	}
+}
+
+type testOrderBody struct {
+	Amount int    `json:"amount"`
+	Remark string `json:"remark"`
+}
+
+type testPageReq struct {
+	Page int `query:"page"`
+}
+
+type testOrderReq struct {
+	testPageReq
+	Id     int64          `path:"id"`
+	Tags   []string       `query:"tags"`
+	Tenant string         `header:"x-tenant"`
+	Body   *testOrderBody `body:""`
+	User   *UserPrincipal `user:""`
+}
+
+func TestBindStruct(t *testing.T) {
+	server := NewServer(Config{AuthEnable: true})
+	server.Init()
+	server.App.Post("/order/:id", Bind(func(req *testOrderReq) error {
+		return lang.NewOk(map[string]any{
+			"id": req.Id, "page": req.Page, "tags": req.Tags, "tenant": req.Tenant,
+			"amount": req.Body.Amount, "user": req.User.Username,
+		})
+	}))
+
+	token, _ := server.MakeToken(1, "tom", testSecret)
+	request, _ := http.NewRequest(http.MethodPost, "/order/9?page=2&tags=a,b", strings.NewReader(`{"amount":100}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("x-token", token)
+	request.Header.Set("x-tenant", "t1")
+	msg := testMsg(t, server, request)
+	data, ok := msg.Data.(map[string]any)
+	if !ok || data["id"] != float64(9) || data["page"] != float64(2) || data["tenant"] != "t1" ||
+		data["amount"] != float64(100) || data["user"] != "tom" || len(data["tags"].([]any)) != 2 {
+		t.Fatal(msg)
+	}
+}
+
+type testUserReq struct {
+	Name  string   `json:"name" validate:"required,max=4"`
+	Age   int      `json:"age" validate:"min=1,max=150"`
+	Email string   `json:"email" validate:"omitempty,email"`
+	Sex   string   `json:"sex" validate:"omitempty,oneof=m f"`
+	Tags  []string `json:"tags" validate:"max=2"`
+}
+
+func (r *testUserReq) Validate() error {
+	if r.Name == "root" {
+		return FieldErrors{{Field: "name", Msg: "reserved"}}
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	server := newBindServer()
+	server.App.Post("/user", UseBody(func(req *testUserReq) error {
+		return lang.NewOk(req.Name)
+	}, func() *testUserReq { return new(testUserReq) }))
+	server.App.Get("/user", Bind(func(req *struct {
+		Id int `query:"id" validate:"required"`
+	}) error {
+		return lang.NewOk(req.Id)
+	}))
+
+	post := func(body string) *lang.Msg {
+		request, _ := http.NewRequest(http.MethodPost, "/user", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		return testMsg(t, server, request)
+	}
+	if msg := post(`{"name":"tom","age":20,"email":"tom@a.com","sex":"m"}`); !msg.IsOk() || msg.Data != "tom" {
+		t.Fatal(msg)
+	}
+	msg := post(`{"name":"jerry","age":200,"email":"x","sex":"u","tags":["a","b","c"]}`)
+	fields, _ := msg.Data.([]any)
+	if msg.Code != CodeValidate || len(fields) != 5 {
+		t.Fatal(msg)
+	}
+	if f := fields[0].(map[string]any); f["field"] != "name" || f["msg"] != "length max 4" {
+		t.Fatal(f)
+	}
+	if msg = post(`{"name":"tom","age":0}`); msg.Code != CodeValidate || len(msg.Data.([]any)) != 1 ||
+		msg.Data.([]any)[0].(map[string]any)["msg"] != "min 1" {
+		t.Fatal(msg)
+	}
+	if msg = post(`{"name":"root","age":20}`); msg.Code != CodeValidate || msg.Data.([]any)[0].(map[string]any)["msg"] != "reserved" {
+		t.Fatal(msg)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, "/user", nil)
+	if msg = testMsg(t, server, request); msg.Code != CodeValidate || msg.Data.([]any)[0].(map[string]any)["field"] != "id" {
+		t.Fatal(msg)
+	}
+}
+
+type testEmbedReq struct {
+	N string `json:"n" validate:"required"`
+}
+
+func TestValidateEmbedded(t *testing.T) {
+	req := &struct {
+		testEmbedReq
+		Age int `json:"age" validate:"min=1"`
+	}{Age: 1}
+	err := Validate(req)
+	if msg, ok := err.(*lang.Msg); !ok || msg.Code != CodeValidate || msg.Data.([]FieldError)[0].Field != "n" {
+		t.Fatal(err)
+	}
+	req.N = "x"
+	if err = Validate(req); err != nil {
+		t.Fatal(err)
+	}
+}
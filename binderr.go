@@ -0,0 +1,107 @@
+package web
+
+import (
+	"errors"
+	"github.com/elancom/go-util/lang"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+// CodeBind 参数绑定失败消息码, Data 为 BindErrData
+const CodeBind = 420
+
+// BindDetail 绑定错误明细级别
+type BindDetail int
+
+const (
+	BindDetailParam BindDetail = iota // 默认: 参数名/位置/来源
+	BindDetailNone                    // 仅消息码
+	BindDetailCause                   // 参数及底层错误(调试)
+)
+
+// BindError 参数绑定错误
+type BindError struct {
+	Index  int    // 参数位置(从1开始), 0:未知
+	Name   string // 参数名
+	Source string // 来源: path/query/header/param/body/user/file
+	Cause  error  // 底层错误
+}
+
+func newBindErr(name string, source string, cause error) *BindError {
+	return &BindError{Name: name, Source: source, Cause: cause}
+}
+
+func (e *BindError) Error() string {
+	s := e.param() + " resolve err"
+	if e.Cause != nil {
+		s += ": " + e.Cause.Error()
+	}
+	return s
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Cause
+}
+
+// 参数名, 无名称时为位置(p1, p2...)
+func (e *BindError) param() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return "p" + strconv.Itoa(e.Index)
+}
+
+// 补充参数名(副本, 解析错误在请求内共享)
+func (e *BindError) withName(name string) *BindError {
+	be := *e
+	if be.Name == "" {
+		be.Name = name
+	}
+	return &be
+}
+
+// BindErrData 绑定错误消息数据
+type BindErrData struct {
+	Index  int    `json:"index,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Source string `json:"source,omitempty"`
+	Cause  string `json:"cause,omitempty"`
+}
+
+// 转绑定错误, 补充参数位置
+func toBindErr(err error, index int) *BindError {
+	be := &BindError{Cause: err}
+	if errors.As(err, &be) {
+		copied := *be
+		be = &copied
+	}
+	if be.Index == 0 {
+		be.Index = index
+	}
+	return be
+}
+
+// 是否认证错误(由错误处理转为对应消息码)
+func isAuthErr(err error) bool {
+	return err == lang.NotAuthorized || err == TokenExpired || err == TokenNotActive || err == TokenRevoked
+}
+
+// 请求内容来源
+func sourceOf(c *fiber.Ctx) string {
+	return lang.Ifs(hasBody(c), sourceBody, sourceQuery)
+}
+
+// 绑定错误消息
+func (s *Server) bindErrMsg(e *BindError) *lang.Msg {
+	switch s.config.BindErrDetail {
+	case BindDetailNone:
+		return lang.NewMsg(CodeBind, "param err")
+	case BindDetailCause:
+		data := BindErrData{Index: e.Index, Name: e.Name, Source: e.Source}
+		if e.Cause != nil {
+			data.Cause = e.Cause.Error()
+		}
+		return lang.NewMsg(CodeBind, e.param()+" resolve err", data)
+	}
+	return lang.NewMsg(CodeBind, e.param()+" resolve err", BindErrData{Index: e.Index, Name: e.Name, Source: e.Source})
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"sync"
+	"time"
+)
+
+// 过期清理间隔
+const ttlSweepInterval = time.Minute
+
+// 带过期时间的内存表
+type ttlMap[K comparable, V any] struct {
+	mu    sync.Mutex
+	items map[K]ttlItem[V]
+	sweep time.Time
+}
+
+type ttlItem[V any] struct {
+	value  V
+	expire time.Time // 零值:不过期
+}
+
+func newTtlMap[K comparable, V any]() *ttlMap[K, V] {
+	m := new(ttlMap[K, V])
+	m.items = make(map[K]ttlItem[V])
+	return m
+}
+
+// 写入, ttl<=0不过期
+func (m *ttlMap[K, V]) put(key K, value V, ttl time.Duration, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweepLocked(now)
+	m.items[key] = ttlItem[V]{value: value, expire: expireAt(ttl, now)}
+}
+
+// 不存在时写入, 返回是否写入
+func (m *ttlMap[K, V]) putIfAbsent(key K, value V, ttl time.Duration, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweepLocked(now)
+	if it, ok := m.items[key]; ok && !it.expired(now) {
+		return false
+	}
+	m.items[key] = ttlItem[V]{value: value, expire: expireAt(ttl, now)}
+	return true
+}
+
+func (m *ttlMap[K, V]) get(key K, now time.Time) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it, ok := m.items[key]
+	if !ok || it.expired(now) {
+		var zero V
+		return zero, false
+	}
+	return it.value, true
+}
+
+func (m *ttlMap[K, V]) sweepLocked(now time.Time) {
+	if now.Sub(m.sweep) < ttlSweepInterval {
+		return
+	}
+	m.sweep = now
+	for k, it := range m.items {
+		if it.expired(now) {
+			delete(m.items, k)
+		}
+	}
+}
+
+func (it ttlItem[V]) expired(now time.Time) bool {
+	return !it.expire.IsZero() && now.After(it.expire)
+}
+
+func expireAt(ttl time.Duration, now time.Time) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// 请求上下文键, 与 c.Locals 共用存储, 加前缀避免与业务键冲突
+const (
+	ctxKeyServer  = "__web_server"
+	ctxKeyParams  = "__web_params"
+	ctxKeyPolicy  = "__web_policy"
+	ctxKeyGrants  = "__web_grants"
+	ctxKeyCodec   = "__web_codec"
+	ctxKeyUploads = "__web_uploads"
+)
+
+// 请求内缓存项
+type ctxEntry[T any] struct {
+	value T
+	err   error
+}
+
+// 请求内缓存: 首次访问时加载, 结果(含错误)保存在请求上下文, 请求结束随上下文释放
+func ctxCached[T any](c *fiber.Ctx, key string, load func() (T, error)) (T, error) {
+	if e, ok := c.Context().UserValue(key).(*ctxEntry[T]); ok {
+		return e.value, e.err
+	}
+	e := new(ctxEntry[T])
+	e.value, e.err = load()
+	c.Context().SetUserValue(key, e)
+	return e.value, e.err
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/elancom/go-util/crypto"
+	"github.com/elancom/go-util/lang"
+	"github.com/elancom/go-util/sign"
+	web "github.com/elancom/go-web"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Doer 请求执行器(*http.Client)
+type Doer interface {
+	Do(request *http.Request) (*http.Response, error)
+}
+
+// Client 与服务端 x-token/x-sign/x-enc 协议一致的客户端
+type Client struct {
+	BaseURL string // 服务地址, 如 http://127.0.0.1:8080
+	Token   string // x-token
+	Secret  string // 通信秘钥
+
+	Sign        bool         // 请求签名(x-sign)
+	SignMode    web.SignMode // 签名方式, 与服务端一致
+	SignHeaders []string     // 规范请求签名包含的请求头
+	Replay      bool         // 防重放(x-timestamp/x-nonce)
+	Enc         string       // 请求加密版本(x-enc), "":不加密
+	EncAad      bool         // x-enc:2 附加数据, 与服务端一致
+	VerifyResp  bool         // 验证响应签名
+
+	Header http.Header // 附加请求头
+	Doer   Doer        // 默认 http.DefaultClient
+}
+
+// New 创建客户端
+func New(baseURL string, token string, secret string) *Client {
+	c := new(Client)
+	c.BaseURL = baseURL
+	c.Token = token
+	c.Secret = secret
+	return c
+}
+
+// Get GET请求
+func (c *Client) Get(path string, query url.Values) (*lang.Msg, error) {
+	return c.Do(http.MethodGet, path, query, nil)
+}
+
+// Post POST请求, body为结构体时按JSON发送
+func (c *Client) Post(path string, body any) (*lang.Msg, error) {
+	return c.Do(http.MethodPost, path, nil, body)
+}
+
+// Put PUT请求
+func (c *Client) Put(path string, body any) (*lang.Msg, error) {
+	return c.Do(http.MethodPut, path, nil, body)
+}
+
+// Patch PATCH请求
+func (c *Client) Patch(path string, body any) (*lang.Msg, error) {
+	return c.Do(http.MethodPatch, path, nil, body)
+}
+
+// Delete DELETE请求(查询串)
+func (c *Client) Delete(path string, query url.Values) (*lang.Msg, error) {
+	return c.Do(http.MethodDelete, path, query, nil)
+}
+
+// Do 发送请求并解析响应消息
+func (c *Client) Do(method string, path string, query url.Values, body any) (*lang.Msg, error) {
+	request, err := c.NewRequest(method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	doer := c.Doer
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+	resp, err := doer.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return c.ReadMsg(request, resp)
+}
+
+// NewRequest 构建请求: 加密 -> 签名
+func (c *Client) NewRequest(method string, path string, query url.Values, body any) (*http.Request, error) {
+	u, err := url.Parse(strings.TrimRight(c.BaseURL, "/") + path)
+	if err != nil {
+		return nil, err
+	}
+	method = strings.ToUpper(method)
+	aad := c.aad(method, u.EscapedPath())
+
+	// 查询串
+	rawQuery := ""
+	if len(query) > 0 {
+		rawQuery = query.Encode()
+		if c.Enc != "" {
+			if rawQuery, err = web.EncryptPayload(c.Enc, []byte(rawQuery), c.Secret, aad); err != nil {
+				return nil, err
+			}
+		}
+	}
+	u.RawQuery = rawQuery
+
+	// 请求体
+	var payload []byte
+	if body != nil {
+		switch b := body.(type) {
+		case []byte:
+			payload = b
+		case string:
+			payload = []byte(b)
+		default:
+			if payload, err = json.Marshal(body); err != nil {
+				return nil, err
+			}
+		}
+		if c.Enc != "" {
+			enc, err := web.EncryptPayload(c.Enc, payload, c.Secret, aad)
+			if err != nil {
+				return nil, err
+			}
+			payload = []byte(enc)
+		}
+	}
+
+	request, err := http.NewRequest(method, u.String(), strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range c.Header {
+		for _, v := range vs {
+			request.Header.Add(k, v)
+		}
+	}
+	if body != nil && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		request.Header.Set("x-token", c.Token)
+	}
+	if c.Enc != "" {
+		request.Header.Set("x-enc", c.Enc)
+	}
+	if c.Sign {
+		c.sign(request, u.EscapedPath(), rawQuery, payload)
+	}
+	return request, nil
+}
+
+// 签名
+func (c *Client) sign(request *http.Request, path string, rawQuery string, payload []byte) {
+	ts, nonce := "", ""
+	if c.Replay {
+		ts = strconv.FormatInt(time.Now().UnixMilli(), 10)
+		nonce = crypto.NewId32()
+		request.Header.Set("x-timestamp", ts)
+		request.Header.Set("x-nonce", nonce)
+	}
+
+	if c.SignMode == web.SignModeCanonical {
+		names := c.SignHeaders
+		if c.Replay {
+			names = append([]string{"x-timestamp", "x-nonce"}, names...)
+		}
+		headers := make(map[string]string, len(names))
+		for _, name := range names {
+			headers[strings.ToLower(name)] = request.Header.Get(name)
+		}
+		canonical := web.CanonicalRequest(request.Method, path, rawQuery, headers, payload)
+		request.Header.Set("x-sign", web.SignCanonical(canonical, c.Secret))
+		return
+	}
+
+	ss := rawQuery
+	if hasBody(request.Method, payload) {
+		ss = strings.Trim(string(payload), `"`)
+	}
+	if c.Replay {
+		ss = web.ReplaySignStr(ss, ts, nonce)
+	}
+	request.Header.Set("x-sign", sign.Str(ss, c.Secret))
+}
+
+// ReadMsg 解析响应: 验签 -> 解密 -> 消息
+func (c *Client) ReadMsg(request *http.Request, resp *http.Response) (*lang.Msg, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if c.VerifyResp && !web.VerifyResponse(body, c.Secret, resp.Header.Get("x-sign")) {
+		return nil, errors.New("response sign err")
+	}
+	if version := resp.Header.Get("x-enc"); version != "" && version != "0" {
+		aad := c.aad(request.Method, request.URL.EscapedPath())
+		if body, err = web.DecryptPayload(version, string(body), c.Secret, aad); err != nil {
+			return nil, errors.New("response dec err")
+		}
+	}
+	msg := new(lang.Msg)
+	if err = json.Unmarshal(body, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// 请求内容是否在请求体, 与服务端一致
+func hasBody(method string, payload []byte) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	case http.MethodDelete:
+		return len(payload) > 0
+	}
+	return false
+}
+
+func (c *Client) aad(method string, path string) []byte {
+	if !c.EncAad {
+		return nil
+	}
+	return web.EncAad(method, path)
+}
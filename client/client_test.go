@@ -0,0 +1,100 @@
+package client
+
+import (
+	"github.com/elancom/go-util/lang"
+	web "github.com/elancom/go-web"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+type appDoer struct {
+	app *fiber.App
+}
+
+func (d appDoer) Do(request *http.Request) (*http.Response, error) {
+	return d.app.Test(request)
+}
+
+func newTestServer(config web.Config) *web.Server {
+	server := web.NewServer(config)
+	server.Init()
+	server.App.Get("/get", web.UseParam(func(name string) error {
+		return lang.NewOk(name)
+	}, "name"))
+	body := web.UseBody(func(m *map[string]string) error {
+		return lang.NewOk((*m)["name"])
+	}, func() *map[string]string { return &map[string]string{} })
+	server.App.Post("/post", body)
+	server.App.Put("/put", body)
+	server.App.Patch("/patch", body)
+	server.App.Get("/file/:name", web.UseParam(func(name string) error {
+		return lang.NewOk(name)
+	}, "name"))
+	server.App.Delete("/delete", web.UseParam(func(name string) error {
+		return lang.NewOk(name)
+	}, "name"))
+	return server
+}
+
+func TestClient(t *testing.T) {
+	configs := []web.Config{
+		{AuthEnable: true, SignEnable: true, EncEnable: true},
+		{AuthEnable: true, SignEnable: true, EncEnable: true, EncAad: true, RespSignEnable: true, SignReplayEnable: true},
+		{AuthEnable: true, SignEnable: true, EncEnable: true, SignMode: web.SignModeCanonical, SignHeaders: []string{"x-tenant"}},
+	}
+	for i, config := range configs {
+		server := newTestServer(config)
+		token, err := server.MakeToken(1, "tom", "1ktk0zzv9RKYInbd")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := New("http://localhost", token, "1ktk0zzv9RKYInbd")
+		c.Doer = appDoer{server.App}
+		c.Sign = true
+		c.SignMode = config.SignMode
+		c.SignHeaders = config.SignHeaders
+		c.Replay = config.SignReplayEnable
+		c.EncAad = config.EncAad
+		c.VerifyResp = config.RespSignEnable
+		c.Header = http.Header{"X-Tenant": {"t1"}}
+
+		// 缺少签名
+		c.Sign = false
+		if msg, err := c.Put("/put", map[string]string{"name": "put"}); err != nil || msg.Msg != "x-sign missing" {
+			t.Fatal(i, msg, err)
+		}
+		c.Sign = true
+
+		for _, enc := range []string{"", web.EncV1, web.EncV2} {
+			c.Enc = enc
+			msg, err := c.Get("/get", url.Values{"name": {"tom"}})
+			if err != nil || !msg.IsOk() || msg.Data != "tom" {
+				t.Fatal(i, enc, msg, err)
+			}
+			// 编码路径(按原始路径签名/加密关联)
+			msg, err = c.Get("/file/"+url.PathEscape("报表 1"), url.Values{"v": {"1"}})
+			if err != nil || !msg.IsOk() {
+				t.Fatal(i, enc, msg, err)
+			}
+			msg, err = c.Post("/post", map[string]string{"name": "jerry"})
+			if err != nil || !msg.IsOk() || msg.Data != "jerry" {
+				t.Fatal(i, enc, msg, err)
+			}
+			msg, err = c.Put("/put", map[string]string{"name": "put"})
+			if err != nil || !msg.IsOk() || msg.Data != "put" {
+				t.Fatal(i, enc, msg, err)
+			}
+			msg, err = c.Patch("/patch", map[string]string{"name": "patch"})
+			if err != nil || !msg.IsOk() || msg.Data != "patch" {
+				t.Fatal(i, enc, msg, err)
+			}
+			msg, err = c.Delete("/delete", url.Values{"name": {"del"}})
+			if err != nil || !msg.IsOk() || msg.Data != "del" {
+				t.Fatal(i, enc, msg, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/elancom/go-util/lang"
+	"github.com/gofiber/fiber/v2"
+	"mime"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 消息编解码(内容协商)
+// 响应编码: 路由选项(WithCodec) > Accept 请求头(仅 Config.Codecs 中的类型) > JSON
+// 请求体按 Content-Type 选择编解码解析(ResolveBody/结构体绑定)
+// 按参数名取值(ResolveParam等)需编解码支持解码为 map[string]any, 否则返回绑定错误
+// 内置 JSON/XML, MessagePack/Protobuf 等由使用方注册:
+//
+//	web.RegisterCodec(msgpackCodec{}, "application/x-msgpack")
+//	app.Get("/items", web.WithCodec("application/msgpack"), handler)
+
+// Codec 编解码
+type Codec interface {
+	ContentType() string // 响应Content-Type
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// 内置编解码
+var (
+	JsonCodec Codec = jsonCodec{}
+	XmlCodec  Codec = xmlCodec{}
+)
+
+// 编解码注册表 Content-Type => Codec
+var codecs = struct {
+	mu    sync.RWMutex
+	types map[string]Codec
+}{types: map[string]Codec{}}
+
+func init() {
+	RegisterCodec(JsonCodec)
+	RegisterCodec(XmlCodec, "text/xml")
+}
+
+// RegisterCodec 注册编解码, contentTypes 为附加的请求Content-Type(别名), 重复注册覆盖
+func RegisterCodec(codec Codec, contentTypes ...string) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+	codecs.types[codec.ContentType()] = codec
+	for _, it := range contentTypes {
+		codecs.types[strings.ToLower(it)] = codec
+	}
+}
+
+// CodecOf 按Content-Type取编解码(忽略参数), 未注册返回nil
+func CodecOf(contentType string) Codec {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+	return codecs.types[mediaType]
+}
+
+// WithCodec 路由选项: 指定响应编码
+func WithCodec(contentType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		codec := CodecOf(contentType)
+		if codec == nil {
+			return lang.NewErr("codec not support: " + contentType)
+		}
+		c.Context().SetUserValue(ctxKeyCodec, &ctxEntry[Codec]{value: codec})
+		return c.Next()
+	}
+}
+
+// 响应编码
+func codecOf(c *fiber.Ctx) Codec {
+	codec, _ := ctxCached(c, ctxKeyCodec, func() (Codec, error) {
+		s := serverOf(c)
+		if s == nil || len(s.config.Codecs) == 0 || c.Get(fiber.HeaderAccept) == "" {
+			return JsonCodec, nil
+		}
+		offers := append([]string{JsonCodec.ContentType()}, s.config.Codecs...)
+		if codec := CodecOf(c.Accepts(offers...)); codec != nil {
+			return codec, nil
+		}
+		return JsonCodec, nil
+	})
+	return codec
+}
+
+// 消息编码
+func encodeMsg(c *fiber.Ctx, msg *lang.Msg) ([]byte, Codec, error) {
+	codec := codecOf(c)
+	b, err := codec.Marshal(msg)
+	return b, codec, err
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return fiber.MIMEApplicationJSON }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string { return fiber.MIMEApplicationXML }
+
+// Marshal 消息编码为 <msg><code/><msg/><data/></msg>, map/切片数据逐项展开
+func (xmlCodec) Marshal(v any) ([]byte, error) {
+	if msg, ok := v.(*lang.Msg); ok {
+		v = xmlMsg{Code: msg.Code, Msg: msg.Msg, Data: xmlAny{msg.Data}}
+	}
+	return xml.Marshal(v)
+}
+
+// Unmarshal 解码, map[string]any 时根元素的子元素为键(见 xmlNode)
+func (xmlCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(*map[string]any); ok {
+		var root xmlNode
+		if err := xml.Unmarshal(data, &root); err != nil {
+			return err
+		}
+		*m = root.toMap()
+		return nil
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// XML元素: 无子元素为文本, 子元素均为 <item> 时为数组, 否则为map(重复元素为数组)
+type xmlNode struct {
+	XMLName xml.Name
+	Content string    `xml:",chardata"`
+	Nodes   []xmlNode `xml:",any"`
+}
+
+func (n xmlNode) value() any {
+	if len(n.Nodes) == 0 {
+		return strings.TrimSpace(n.Content)
+	}
+	items := make([]any, 0, len(n.Nodes))
+	for _, it := range n.Nodes {
+		if it.XMLName.Local != "item" {
+			return n.toMap()
+		}
+		items = append(items, it.value())
+	}
+	return items
+}
+
+func (n xmlNode) toMap() map[string]any {
+	m := make(map[string]any, len(n.Nodes))
+	for _, it := range n.Nodes {
+		key, v := it.XMLName.Local, it.value()
+		switch old := m[key].(type) {
+		case nil:
+			m[key] = v
+		case []any:
+			m[key] = append(old, v)
+		default:
+			m[key] = []any{old, v}
+		}
+	}
+	return m
+}
+
+type xmlMsg struct {
+	XMLName xml.Name `xml:"msg"`
+	Code    int      `xml:"code"`
+	Msg     string   `xml:"msg,omitempty"`
+	Data    xmlAny   `xml:"data,omitempty"`
+}
+
+// 任意值XML编码: map 键为元素名, 切片元素为 <item>
+type xmlAny struct {
+	v any
+}
+
+func (x xmlAny) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if x.v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(x.v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		values := make(map[string]reflect.Value, rv.Len())
+		for _, k := range rv.MapKeys() {
+			key := xmlName(k)
+			keys = append(keys, key)
+			values[key] = rv.MapIndex(k)
+		}
+		sort.Strings(keys)
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := e.EncodeElement(xmlAny{values[k].Interface()}, xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.EncodeElement(xmlAny{rv.Index(i).Interface()}, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+	return e.EncodeElement(rv.Interface(), start)
+}
+
+func xmlName(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	b, _ := json.Marshal(k.Interface())
+	return strings.Trim(string(b), `"`)
+}
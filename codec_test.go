@@ -0,0 +1,133 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/elancom/go-util/lang"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// 测试编解码: "T:" + JSON
+type testCodec struct{}
+
+func (testCodec) ContentType() string { return "application/x-test" }
+
+func (testCodec) Marshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return append([]byte("T:"), b...), err
+}
+
+func (testCodec) Unmarshal(data []byte, v any) error {
+	if !bytes.HasPrefix(data, []byte("T:")) {
+		return errors.New("test codec err")
+	}
+	return json.Unmarshal(data[2:], v)
+}
+
+type testItem struct {
+	Name string `json:"name" xml:"name"`
+	Qty  int    `json:"qty" xml:"qty"`
+}
+
+func TestCodec(t *testing.T) {
+	RegisterCodec(testCodec{})
+	server := NewServer(Config{AuthEnable: true, EncEnable: true, Codecs: []string{"application/xml"}})
+	server.Init()
+	server.Secure(server.App, Policy{}, "/pub/**")
+	handle := UseBody(func(item *testItem) error {
+		return lang.NewOk(map[string]any{"name": item.Name, "tags": []string{"a", "b"}})
+	}, func() *testItem { return new(testItem) })
+	server.App.Post("/pub/item", handle)
+	server.App.Post("/pub/test", WithCodec("application/x-test"), Bind2(func(item *testItem, qty int) error {
+		return lang.NewOk(item.Name + ":" + string(rune('0'+qty)))
+	}, ResolveBody(func() *testItem { return new(testItem) }), ResolveInt[int]("qty")))
+	server.App.Post("/enc/item", handle)
+
+	post := func(url string, contentType string, accept string, body string) (*http.Response, string) {
+		request, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		request.Header.Set("Content-Type", contentType)
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		return testBody(t, server, request)
+	}
+
+	// 默认JSON
+	if _, body := post("/pub/item", "application/json", "", `{"name":"tom"}`); body != `{"code":200,"data":{"name":"tom","tags":["a","b"]}}` {
+		t.Fatal(body)
+	}
+	// Accept协商XML, 请求体XML
+	resp, body := post("/pub/item", "application/xml", "application/xml", `<testItem><name>tom</name></testItem>`)
+	if resp.Header.Get("Content-Type") != "application/xml" ||
+		body != `<msg><code>200</code><data><name>tom</name><tags><item>a</item><item>b</item></tags></data></msg>` {
+		t.Fatal(body)
+	}
+	// 未在 Config.Codecs 中的类型不参与协商
+	if resp, _ = post("/pub/item", "application/json", "application/x-test", `{"name":"tom"}`); resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatal(resp)
+	}
+	// 路由选项, 请求体按编解码解析
+	if resp, body = post("/pub/test", "application/x-test", "", `T:{"name":"tom","qty":3}`); body != `T:{"code":200,"data":"tom:3"}` ||
+		resp.Header.Get("Content-Type") != "application/x-test" {
+		t.Fatal(body)
+	}
+
+	// 加密前按协商编码
+	token, _ := server.MakeToken(1, "tom", testSecret)
+	request, _ := http.NewRequest(http.MethodPost, "/enc/item", strings.NewReader(`{"name":"tom"}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/xml")
+	request.Header.Set("x-token", token)
+	resp, body = testBody(t, server, request)
+	plain, err := DecryptPayload(resp.Header.Get("x-enc"), body, testSecret, nil)
+	if err != nil || !strings.HasPrefix(string(plain), "<msg><code>200</code>") {
+		t.Fatal(body, err)
+	}
+}
+
+// 测试编解码: 仅支持解码为 testItem(不支持map)
+type testItemCodec struct{}
+
+func (testItemCodec) ContentType() string { return "application/x-item" }
+
+func (testItemCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (testItemCodec) Unmarshal(data []byte, v any) error {
+	if _, ok := v.(*testItem); !ok {
+		return errors.New("item codec: type not support")
+	}
+	return json.Unmarshal(data, v)
+}
+
+func TestCodecParams(t *testing.T) {
+	RegisterCodec(testItemCodec{})
+	server := newBindServer()
+	server.App.Post("/param", Bind2(func(name string, tags []string) error {
+		return lang.NewOk(name + ":" + strings.Join(tags, ","))
+	}, ResolveParam("name"), ResolveSlice[string]("tags")))
+	server.App.Post("/body", UseBody(func(item *testItem) error {
+		return lang.NewOk(item.Name)
+	}, func() *testItem { return new(testItem) }))
+
+	post := func(url string, contentType string, body string) *lang.Msg {
+		request, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		request.Header.Set("Content-Type", contentType)
+		return testMsg(t, server, request)
+	}
+
+	// XML请求体按参数名取值
+	if msg := post("/param", "application/xml", `<req><name>tom</name><tags><item>a</item><item>b</item></tags></req>`); msg.Data != "tom:a,b" {
+		t.Fatal(msg)
+	}
+	// 不支持map的编解码: 按参数名取值返回绑定错误, 整体解码正常
+	msg := post("/param", "application/x-item", `{"name":"tom"}`)
+	if data, _ := msg.Data.(map[string]any); msg.Code != CodeBind || data["source"] != "body" {
+		t.Fatal(msg)
+	}
+	if msg = post("/body", "application/x-item", `{"name":"tom"}`); msg.Data != "tom" {
+		t.Fatal(msg)
+	}
+}
@@ -0,0 +1,216 @@
+package web
+
+import (
+	cr "crypto/rand"
+	"encoding/base64"
+	"github.com/elancom/go-util/bytes"
+	"github.com/elancom/go-util/crypto"
+	. "github.com/elancom/go-util/lang"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// 加密版本(x-enc)
+const (
+	EncV1 = "1" // AES-ECB, base64(密文)
+	EncV2 = "2" // AES-GCM, base64(nonce+密文), 可选 "METHOD /path" 作为附加数据
+)
+
+// 默认接受的加密版本
+var defaultEncVersions = []string{EncV1, EncV2}
+
+// EncryptPayload 加密(秘钥为用户通信秘钥)
+func EncryptPayload(version string, plain []byte, secret string, aad []byte) (string, error) {
+	switch version {
+	case EncV1:
+		sb, err := crypto.AesEcbEncrypt(plain, []byte(secret))
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(sb), nil
+	case EncV2:
+		aead, err := newGcm([]byte(secret))
+		if err != nil {
+			return "", err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err = cr.Read(nonce); err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, aad)), nil
+	}
+	return "", NewErr("x-enc not support")
+}
+
+// DecryptPayload 解密
+func DecryptPayload(version string, s string, secret string, aad []byte) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case EncV1:
+		return crypto.AesEcbDecrypt(b, []byte(secret))
+	case EncV2:
+		aead, err := newGcm([]byte(secret))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < aead.NonceSize() {
+			return nil, NewErr("dec err")
+		}
+		return aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], aad)
+	}
+	return nil, NewErr("x-enc not support")
+}
+
+// EncAad x-enc:2 附加数据, path 为原始请求路径(不解码)
+func EncAad(method string, path string) []byte {
+	return []byte(strings.ToUpper(method) + " " + path)
+}
+
+func (s *Server) encVersions() []string {
+	if len(s.config.EncVersions) > 0 {
+		return s.config.EncVersions
+	}
+	return defaultEncVersions
+}
+
+func (s *Server) encAccepted(version string) bool {
+	for _, it := range s.encVersions() {
+		if it == version {
+			return true
+		}
+	}
+	return false
+}
+
+// 请求是否加密
+func isEncRequest(c *fiber.Ctx) bool {
+	v := c.Get("x-enc")
+	return v != "" && v != "0"
+}
+
+// 响应加密版本: 与请求一致, 否则使用配置版本
+func (s *Server) respEncVersion(c *fiber.Ctx) string {
+	if v := c.Get("x-enc"); isEncRequest(c) && s.encAccepted(v) {
+		return v
+	}
+	if s.config.EncVersion != "" {
+		return s.config.EncVersion
+	}
+	return s.encVersions()[0]
+}
+
+func (s *Server) encAad(c *fiber.Ctx, version string) []byte {
+	if version != EncV2 || !s.config.EncAad {
+		return nil
+	}
+	return EncAad(c.Method(), c.Path())
+}
+
+// 响应加密
+func (s *Server) encryptResponse(c *fiber.Ctx, err error) error {
+	if r, ok := err.(Responder); ok {
+		switch r.EncMode() {
+		case EncSkip:
+			return err
+		case EncReject:
+			return NewErr("response enc not support")
+		}
+	}
+
+	userPrincipal, ok := c.Context().Value("principal").(*UserPrincipal)
+	if !ok {
+		// 认证失败等错误无秘钥可用, 原样返回
+		if msg, isMsg := err.(*Msg); isMsg && msg.IsErr() {
+			return err
+		}
+		return NewErr("user not found")
+	}
+
+	// 转字符串
+	plain := ""
+	switch t := err.(type) {
+	case *Text:
+		log.Println("[将要加密文本]", err.Error())
+		plain = err.Error()
+	case *Msg:
+		b, codec, encErr := encodeMsg(c, t)
+		if encErr != nil {
+			return encErr
+		}
+		log.Println("[将要加密消息]", codec.ContentType(), string(b))
+		plain = string(b)
+	case payloader:
+		plain = string(t.Payload())
+	case Responder:
+		return NewErr("response enc not support")
+	default:
+		// 未知错误
+		return err
+	}
+	if plain == "" {
+		return err
+	}
+
+	version := s.respEncVersion(c)
+	encSs, encErr := EncryptPayload(version, []byte(plain), userPrincipal.Secret, s.encAad(c, version))
+	if encErr != nil {
+		log.Println("[enc]加密错误", encErr)
+		return NewErr("enc err")
+	}
+
+	// 标记加密头
+	c.Response().Header.Set("x-enc", version)
+
+	return NewText(encSs)
+}
+
+// 请求解密
+func (s *Server) decryptRequest(c *fiber.Ctx) error {
+	version := c.Get("x-enc")
+	if !s.encAccepted(version) {
+		return NewErr("x-enc not support")
+	}
+
+	// 从tk中取加密秘钥
+	principal, ok := c.Context().Value("principal").(*UserPrincipal)
+	if !ok {
+		return NewErr("use x-enc, but not found principal")
+	}
+	if principal.Secret == "" {
+		return NewErr("use x-enc, but secret not found")
+	}
+	aad := s.encAad(c, version)
+
+	if !hasBody(c) { // ?*****
+		d3 := string(c.Request().URI().QueryString())
+		log.Println("密文:", d3)
+		if d3 != "" {
+			decrypt, err := DecryptPayload(version, d3, principal.Secret, aad)
+			if err != nil {
+				return NewErr("dec err")
+			}
+			log.Println("解密:", string(decrypt))
+			c.Request().URI().SetQueryStringBytes(decrypt)
+		}
+	} else {
+		body := c.Body()
+		if len(body) > 0 {
+			body = bytes.TrimUint8(body, 34) // 34:双引号
+			log.Println("密文:", string(body))
+			decrypt, err := DecryptPayload(version, string(body), principal.Secret, aad)
+			if err != nil {
+				return NewErr("dec err")
+			}
+			log.Println("解密:", string(decrypt))
+			// 修改内容及长度
+			c.Request().SetBody(decrypt)
+			c.Request().Header.Set("Content-Length", strconv.Itoa(len(decrypt)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"github.com/elancom/go-util/lang"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEncV2(t *testing.T) {
+	server := NewServer(Config{AuthEnable: true, EncEnable: true, EncAad: true})
+	server.Init()
+	server.App.Post("/echo", UseBody(func(m *map[string]string) error {
+		return lang.NewOk((*m)["name"])
+	}, func() *map[string]string { return &map[string]string{} }))
+
+	token, _ := server.MakeToken(1, "tom", testSecret)
+	aad := EncAad(http.MethodPost, "/echo")
+	body, err := EncryptPayload(EncV2, []byte(`{"name":"tom"}`), testSecret, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(version string, path string) *http.Request {
+		request, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		request.Header.Set("x-token", token)
+		request.Header.Set("x-enc", version)
+		request.Header.Set("Content-Type", "application/json")
+		return request
+	}
+
+	resp, err := server.App.Test(request(EncV2, "/echo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("x-enc") != EncV2 {
+		t.Fatal(resp.Header.Get("x-enc"))
+	}
+	b, _ := io.ReadAll(resp.Body)
+	plain, err := DecryptPayload(EncV2, string(b), testSecret, aad)
+	if err != nil {
+		t.Fatal(string(b), err)
+	}
+	if string(plain) != `{"code":200,"data":"tom"}` {
+		t.Fatal(string(plain))
+	}
+
+	// 版本不接受
+	server.config.EncVersions = []string{EncV1}
+	resp, _ = server.App.Test(request(EncV2, "/echo"))
+	if resp.Header.Get("x-enc") == EncV2 {
+		t.Fatal("x-enc 2 accepted")
+	}
+}
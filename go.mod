@@ -5,14 +5,14 @@ go 1.18
 require (
 	github.com/elancom/go-util v1.0.99
 	github.com/gofiber/fiber/v2 v2.34.1
+	github.com/google/uuid v1.3.0
+	github.com/valyala/fasthttp v1.37.0
 )
 
 require (
 	github.com/andybalholm/brotli v1.0.4 // indirect
-	github.com/google/uuid v1.3.0 // indirect
 	github.com/klauspost/compress v1.15.0 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
-	github.com/valyala/fasthttp v1.37.0 // indirect
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	golang.org/x/sys v0.0.0-20220227234510-4e6760a101f9 // indirect
 )
@@ -0,0 +1,248 @@
+package web
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	cr "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JWT签名算法
+const (
+	JwtHS256 = "HS256"
+	JwtRS256 = "RS256"
+	JwtEdDSA = "EdDSA"
+)
+
+// JwtConfig JWT配置
+type JwtConfig struct {
+	Alg        string            // 签名算法
+	Secret     []byte            // HS256秘钥
+	PrivateKey crypto.PrivateKey // RS256(*rsa.PrivateKey)/EdDSA(ed25519.PrivateKey), 仅签发需要
+	PublicKey  crypto.PublicKey  // RS256(*rsa.PublicKey)/EdDSA(ed25519.PublicKey)
+	TTL        time.Duration     // 有效期(exp), 0:不设置
+	Leeway     time.Duration     // exp时钟偏差容忍
+	Issuer     string            // iss(设置后校验)
+	Audience   string            // aud(设置后校验)
+}
+
+// NewJwtCodec JWT令牌
+// 标准声明 sub/iat/jti 对应 Id/Timestamp/Key, username/secret/roles/perms 为自定义声明
+// iat 为秒, 毫秒签发时间另存于 iat_ms(吊销判断按毫秒), 缺失时按 iat
+// 注意: JWT仅签名不加密, secret 对令牌持有者可见
+func NewJwtCodec(conf JwtConfig) TokenCodec {
+	c := new(jwtCodec)
+	c.conf = conf
+	return c
+}
+
+type jwtCodec struct {
+	conf JwtConfig
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+type jwtClaims struct {
+	Sub      string          `json:"sub"`
+	Iat      int64           `json:"iat"`
+	IatMs    int64           `json:"iat_ms,omitempty"`
+	Exp      int64           `json:"exp,omitempty"`
+	Jti      string          `json:"jti"`
+	Iss      string          `json:"iss,omitempty"`
+	Aud      json.RawMessage `json:"aud,omitempty"`
+	Username string          `json:"username"`
+	Secret   string          `json:"secret,omitempty"`
+	Roles    []string        `json:"roles,omitempty"`
+	Perms    []string        `json:"perms,omitempty"`
+}
+
+func (j *jwtCodec) Encode(principal *UserPrincipal) (string, error) {
+	issued := time.UnixMilli(principal.Timestamp)
+	claims := jwtClaims{
+		Sub:      strconv.FormatInt(principal.Id, 10),
+		Iat:      issued.Unix(),
+		IatMs:    issued.UnixMilli(),
+		Jti:      principal.Key,
+		Iss:      j.conf.Issuer,
+		Username: principal.Username,
+		Secret:   principal.Secret,
+		Roles:    principal.Roles,
+		Perms:    principal.Perms,
+	}
+	if j.conf.TTL > 0 {
+		claims.Exp = issued.Add(j.conf.TTL).Unix()
+	}
+	if j.conf.Audience != "" {
+		claims.Aud, _ = json.Marshal(j.conf.Audience)
+	}
+
+	header, err := json.Marshal(jwtHeader{Alg: j.conf.Alg, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := jwtEncode(header) + "." + jwtEncode(payload)
+	sig, err := j.sign([]byte(signing))
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + jwtEncode(sig), nil
+}
+
+func (j *jwtCodec) Decode(token string) (*UserPrincipal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token err(J)")
+	}
+
+	// 头部: 只接受配置的算法
+	hb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("token err(DC)")
+	}
+	header := new(jwtHeader)
+	if err = json.Unmarshal(hb, header); err != nil || header.Alg != j.conf.Alg {
+		return nil, errors.New("token err(alg)")
+	}
+
+	// 签名
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("token err(DC)")
+	}
+	if !j.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, errors.New("token err(0)")
+	}
+
+	// 声明
+	pb, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("token err(DC)")
+	}
+	claims := new(jwtClaims)
+	if err = json.Unmarshal(pb, claims); err != nil {
+		return nil, errors.New("token err(1)")
+	}
+	if claims.Exp > 0 && time.Now().After(time.Unix(claims.Exp, 0).Add(j.conf.Leeway)) {
+		return nil, TokenExpired
+	}
+	if j.conf.Issuer != "" && claims.Iss != j.conf.Issuer {
+		return nil, errors.New("token err(iss)")
+	}
+	if j.conf.Audience != "" && !jwtHasAudience(claims.Aud, j.conf.Audience) {
+		return nil, errors.New("token err(aud)")
+	}
+
+	id, err := strconv.ParseInt(claims.Sub, 10, 64)
+	if err != nil {
+		return nil, errors.New("token err(sub)")
+	}
+	principal := &UserPrincipal{
+		Id:        id,
+		Username:  claims.Username,
+		Key:       claims.Jti,
+		Secret:    claims.Secret,
+		Timestamp: claims.issuedMilli(),
+		Roles:     claims.Roles,
+		Perms:     claims.Perms,
+	}
+	if err = checkPrincipal(principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// 签发时间(毫秒), iat_ms 与 iat 不一致时以 iat 为准
+func (c *jwtClaims) issuedMilli() int64 {
+	if c.IatMs > 0 && c.IatMs/1000 == c.Iat {
+		return c.IatMs
+	}
+	return c.Iat * 1000
+}
+
+func (j *jwtCodec) sign(data []byte) ([]byte, error) {
+	switch j.conf.Alg {
+	case JwtHS256:
+		if len(j.conf.Secret) == 0 {
+			return nil, errors.New("jwt secret missing")
+		}
+		mac := hmac.New(sha256.New, j.conf.Secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case JwtRS256:
+		key, ok := j.conf.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("jwt rsa private key missing")
+		}
+		sum := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(cr.Reader, key, crypto.SHA256, sum[:])
+	case JwtEdDSA:
+		key, ok := j.conf.PrivateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("jwt ed25519 private key missing")
+		}
+		return ed25519.Sign(key, data), nil
+	}
+	return nil, errors.New("jwt alg not support: " + j.conf.Alg)
+}
+
+func (j *jwtCodec) verify(data []byte, sig []byte) bool {
+	switch j.conf.Alg {
+	case JwtHS256:
+		if len(j.conf.Secret) == 0 {
+			return false
+		}
+		mac := hmac.New(sha256.New, j.conf.Secret)
+		mac.Write(data)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case JwtRS256:
+		key, ok := j.conf.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		sum := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) == nil
+	case JwtEdDSA:
+		key, ok := j.conf.PublicKey.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(key, data, sig)
+	}
+	return false
+}
+
+func jwtEncode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// aud 可为字符串或数组
+func jwtHasAudience(raw json.RawMessage, aud string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == aud
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, it := range many {
+			if it == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
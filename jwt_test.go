@@ -0,0 +1,90 @@
+package web
+
+import (
+	"crypto/ed25519"
+	"github.com/elancom/go-util/lang"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJwtServer(t *testing.T) {
+	server := NewServer(Config{
+		AuthEnable: true,
+		Jwt:        &JwtConfig{Alg: JwtHS256, Secret: []byte("jwt-secret"), TTL: time.Hour},
+	})
+	server.Init()
+	server.App.Get("/me", UseUser(func(p *UserPrincipal) error {
+		return lang.NewOk(p.Username)
+	}))
+
+	token, err := server.MakeToken(7, "tom", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ := http.NewRequest(http.MethodGet, "/me", nil)
+	request.Header.Set("x-token", token)
+	if msg := testMsg(t, server, request); !msg.IsOk() || msg.Data != "tom" {
+		t.Fatal(msg)
+	}
+
+	// 篡改声明
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + jwtEncode([]byte(`{"sub":"1","iat":1,"jti":"x","username":"admin"}`)) + "." + parts[2]
+	if _, err = server.GetUserPrincipal(forged); err == nil {
+		t.Fatal("forged token accepted")
+	}
+	// 全部注销后同一秒内重新登录
+	server = NewServer(Config{
+		AuthEnable:      true,
+		Jwt:             &JwtConfig{Alg: JwtHS256, Secret: []byte("jwt-secret"), TTL: time.Hour},
+		RevocationStore: NewMemoryRevocationStore(),
+	})
+	if err = server.RevokeUser(7, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	token, _ = server.MakeToken(7, "tom", testSecret)
+	principal, err := server.GetUserPrincipal(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := server.config.RevocationStore.IsRevoked(principal); revoked {
+		t.Fatal("new token revoked")
+	}
+}
+
+func TestJwtEdDSA(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := NewJwtCodec(JwtConfig{Alg: JwtEdDSA, PrivateKey: priv, PublicKey: pub, Issuer: "web", Audience: "app"})
+	token, err := MakeTokenWith(codec, 7, "tom", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err := codec.Decode(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Id != 7 || principal.Username != "tom" || principal.Secret != testSecret {
+		t.Fatal(principal)
+	}
+
+	// 算法不一致
+	hs := NewJwtCodec(JwtConfig{Alg: JwtHS256, Secret: pub})
+	if _, err = hs.Decode(token); err == nil {
+		t.Fatal("alg confusion accepted")
+	}
+
+	// 过期
+	expired := NewJwtCodec(JwtConfig{Alg: JwtEdDSA, PrivateKey: priv, PublicKey: pub, TTL: time.Second})
+	p := NewUserPrincipal(7, "tom", testSecret)
+	p.Timestamp = time.Now().Add(-time.Minute).UnixMilli()
+	token, _ = expired.Encode(p)
+	if _, err = expired.Decode(token); err != TokenExpired {
+		t.Fatal(err)
+	}
+}
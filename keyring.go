@@ -0,0 +1,233 @@
+package web
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	cr "crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/elancom/go-util/str"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Keyring 秘钥环
+// 每个秘钥有唯一ID, 所有秘钥均可用于解密, 当前秘钥(active)用于加密
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string][]byte
+	active string
+}
+
+// NewKeyring 创建秘钥环
+func NewKeyring() *Keyring {
+	k := new(Keyring)
+	k.keys = make(map[string][]byte)
+	return k
+}
+
+// 单秘钥(不校验长度, 使用时报错)
+func newSingleKeyring(key []byte) *Keyring {
+	k := NewKeyring()
+	k.keys["0"] = key
+	k.active = "0"
+	return k
+}
+
+// Add 添加秘钥(16/24/32位), 第一个添加的秘钥为当前秘钥
+func (k *Keyring) Add(id string, key []byte) error {
+	if str.IsBlank(id) || strings.ContainsAny(id, ".:,\n") {
+		return errors.New("key id err")
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return errors.New("key length err")
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = key
+	if k.active == "" {
+		k.active = id
+	}
+	return nil
+}
+
+// SetActive 设置当前秘钥
+func (k *Keyring) SetActive(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return errors.New("key not found")
+	}
+	k.active = id
+	return nil
+}
+
+// Remove 移除秘钥(当前秘钥不可移除)
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.active {
+		return errors.New("active key can not remove")
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// Active 当前秘钥
+func (k *Keyring) Active() (string, []byte) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active, k.keys[k.active]
+}
+
+// Get 按ID取秘钥
+func (k *Keyring) Get(id string) ([]byte, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	return key, ok
+}
+
+// Ids 全部秘钥ID(当前秘钥在前)
+func (k *Keyring) Ids() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		if id != k.active {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if _, ok := k.keys[k.active]; ok {
+		ids = append([]string{k.active}, ids...)
+	}
+	return ids
+}
+
+// Encrypt AES-GCM加密(格式: kid.base64url(nonce+密文)), 秘钥ID参与认证
+func (k *Keyring) Encrypt(plain []byte, aad []byte) (string, error) {
+	id, key := k.Active()
+	aead, err := newGcm(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = cr.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, keyringAad(id, aad))
+	return id + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt AES-GCM解密, 无秘钥ID时使用当前秘钥
+func (k *Keyring) Decrypt(s string, aad []byte) ([]byte, error) {
+	id, payload := "", s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		id, payload = s[:i], s[i+1:]
+	}
+
+	var key []byte
+	if id == "" {
+		_, key = k.Active()
+	} else {
+		found := false
+		if key, found = k.Get(id); !found {
+			return nil, errors.New("key not found")
+		}
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("cipher text err")
+	}
+	nonce := sealed[:aead.NonceSize()]
+	return aead.Open(nil, nonce, sealed[aead.NonceSize():], keyringAad(id, aad))
+}
+
+func keyringAad(id string, aad []byte) []byte {
+	if id == "" {
+		return aad
+	}
+	return append(append([]byte(id), ':'), aad...)
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ParseKeyring 解析秘钥环
+// 格式: 每项 id:key, 以换行或逗号分隔; *id:key 为当前秘钥(默认第一项);
+// key 以 base64: 开头时按base64解码, 否则按原文; # 开头为注释
+func ParseKeyring(s string) (*Keyring, error) {
+	k := NewKeyring()
+	active := ""
+	items := strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == ',' })
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || str.HasPrefix(item, "#") {
+			continue
+		}
+		i := strings.IndexByte(item, ':')
+		if i <= 0 {
+			return nil, errors.New("keyring item err")
+		}
+		id, key := strings.TrimSpace(item[:i]), []byte(strings.TrimSpace(item[i+1:]))
+		if str.HasPrefix(id, "*") {
+			id = strings.TrimSpace(id[1:])
+			active = id
+		}
+		if str.HasPrefix(string(key), "base64:") {
+			b, err := base64.StdEncoding.DecodeString(string(key[len("base64:"):]))
+			if err != nil {
+				return nil, errors.New("keyring key err: " + id)
+			}
+			key = b
+		}
+		if err := k.Add(id, key); err != nil {
+			return nil, errors.New(err.Error() + ": " + id)
+		}
+	}
+	if len(k.keys) == 0 {
+		return nil, errors.New("keyring empty")
+	}
+	if active != "" {
+		if err := k.SetActive(active); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// LoadKeyringFile 从文件加载秘钥环
+func LoadKeyringFile(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyring(string(b))
+}
+
+// LoadKeyringEnv 从环境变量加载秘钥环
+func LoadKeyringEnv(name string) (*Keyring, error) {
+	s, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, errors.New("env not found: " + name)
+	}
+	return ParseKeyring(s)
+}
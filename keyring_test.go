@@ -0,0 +1,56 @@
+package web
+
+import (
+	"os"
+	"testing"
+)
+
+func TestKeyringRotate(t *testing.T) {
+	ring, err := ParseKeyring("k1:abcdefghijklmnop, k2:base64:MTIzNDU2Nzg5MDEyMzQ1Ng==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := ring.Active(); id != "k1" {
+		t.Fatal(id)
+	}
+
+	codec := NewKeyringTokenCodec(ring)
+	old, err := MakeTokenWith(codec, 1, "tom", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换后旧令牌仍可解析, 新令牌使用新秘钥
+	if err = ring.SetActive("k2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = codec.Decode(old); err != nil {
+		t.Fatal(err)
+	}
+	token, _ := MakeTokenWith(codec, 1, "tom", testSecret)
+	if token[:5] != "2.k2." {
+		t.Fatal(token)
+	}
+
+	// 移除旧秘钥后旧令牌失效
+	if err = ring.Remove("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = codec.Decode(old); err == nil {
+		t.Fatal("removed key accepted")
+	}
+}
+
+func TestLoadKeyringEnv(t *testing.T) {
+	_ = os.Setenv("TEST_KEYRING", "a:abcdefghijklmnop\n*b:1234567890123456")
+	ring, err := LoadKeyringEnv("TEST_KEYRING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, key := ring.Active(); id != "b" || string(key) != "1234567890123456" {
+		t.Fatal(id)
+	}
+	if _, err = ParseKeyring("a:short"); err == nil {
+		t.Fatal("short key accepted")
+	}
+}
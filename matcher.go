@@ -0,0 +1,135 @@
+package web
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// PathMatcher 路径匹配
+// 规则格式: [METHOD[,METHOD] ]pattern
+//
+//	/public        前缀匹配(含 /public 本身), 同 /public/ 及 /public/**
+//	=/login        精确匹配
+//	/api/*/public  通配, * 匹配一段(支持 *.json 等), ** 匹配任意段
+//	GET /api/x     仅匹配指定方法(GET 同时匹配 HEAD, 同fiber路由)
+//
+// 默认同fiber路由: 不区分大小写, 忽略末尾 /
+type PathMatcher struct {
+	CaseSensitive bool // 区分大小写
+	StrictRouting bool // 区分末尾 /
+	rules         []pathRule
+}
+
+type pathRule struct {
+	methods []string // 空:全部方法
+	segs    []string
+	lower   []string // 小写(不区分大小写时匹配)
+}
+
+// NewPathMatcher 创建路径匹配
+func NewPathMatcher(rules ...string) *PathMatcher {
+	m := new(PathMatcher)
+	m.Add(rules...)
+	return m
+}
+
+// Add 添加规则
+func (m *PathMatcher) Add(rules ...string) {
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		r := pathRule{}
+		if i := strings.IndexByte(rule, ' '); i > 0 {
+			for _, method := range strings.Split(rule[:i], ",") {
+				r.methods = append(r.methods, strings.ToUpper(strings.TrimSpace(method)))
+			}
+			rule = strings.TrimSpace(rule[i+1:])
+		}
+		if strings.HasPrefix(rule, "=") {
+			rule = strings.TrimSpace(rule[1:])
+		} else if strings.HasSuffix(rule, "/") || !strings.Contains(rule, "*") {
+			rule = strings.TrimRight(rule, "/") + "/**"
+		}
+		r.segs = splitPath(rule)
+		r.lower = splitPath(strings.ToLower(rule))
+		m.rules = append(m.rules, r)
+	}
+}
+
+// Match 是否匹配
+func (m *PathMatcher) Match(method string, p string) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	if !m.CaseSensitive {
+		p = strings.ToLower(p)
+	}
+	segs := splitPath(p)
+	if m.StrictRouting && len(segs) > 0 && strings.HasSuffix(p, "/") {
+		segs = append(segs, "")
+	}
+	for _, r := range m.rules {
+		pattern := r.lower
+		if m.CaseSensitive {
+			pattern = r.segs
+		}
+		if r.match(method, pattern, segs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r pathRule) match(method string, pattern []string, segs []string) bool {
+	if len(r.methods) > 0 {
+		found := false
+		for _, it := range r.methods {
+			if it == method || it == http.MethodGet && method == http.MethodHead {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matchSegs(pattern, segs)
+}
+
+func matchSegs(pattern []string, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// ** 匹配0或多段
+			for i := 0; i <= len(segs); i++ {
+				if matchSegs(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if pattern[0] != segs[0] {
+			if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+				return false
+			}
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+func splitPath(p string) []string {
+	parts := strings.Split(p, "/")
+	segs := parts[:0]
+	for _, it := range parts {
+		if it != "" {
+			segs = append(segs, it)
+		}
+	}
+	return segs
+}
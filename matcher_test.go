@@ -0,0 +1,54 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPathMatcher(t *testing.T) {
+	m := NewPathMatcher("=/login", "/public/**", "/static/", "/open", "/api/*/public", "GET,HEAD /doc/*.json")
+	cases := []struct {
+		method string
+		path   string
+		match  bool
+	}{
+		{http.MethodPost, "/login", true},
+		{http.MethodPost, "/login/x", false},
+		{http.MethodGet, "/public", true},
+		{http.MethodGet, "/public/a/b", true},
+		{http.MethodGet, "/publicity", false},
+		{http.MethodGet, "/open", true},
+		{http.MethodGet, "/open/x", true},
+		{http.MethodGet, "/opener", false},
+		{http.MethodGet, "/static/app.js", true},
+		{http.MethodGet, "/api/v1/public", true},
+		{http.MethodGet, "/api/v1/private", false},
+		{http.MethodGet, "/api/v1/x/public", false},
+		{http.MethodGet, "/doc/api.json", true},
+		{http.MethodPost, "/doc/api.json", false},
+		{http.MethodGet, "/", false},
+	}
+	for _, it := range cases {
+		if m.Match(it.method, it.path) != it.match {
+			t.Error(it.method, it.path, !it.match)
+		}
+	}
+	if NewPathMatcher("/**").Match(http.MethodGet, "/") != true {
+		t.Error("/** should match /")
+	}
+	if !NewPathMatcher("/public").Match(http.MethodGet, "/public/x") {
+		t.Error("/public should match /public/x")
+	}
+	if !m.Match(http.MethodPost, "/LOGIN/") || !NewPathMatcher("GET /Doc").Match(http.MethodHead, "/doc") {
+		t.Error("case/trailing slash/HEAD should match")
+	}
+
+	strict := NewPathMatcher("=/Login", "/public/**")
+	strict.CaseSensitive, strict.StrictRouting = true, true
+	if strict.Match(http.MethodPost, "/login") || strict.Match(http.MethodPost, "/Login/") {
+		t.Error("strict should not match")
+	}
+	if !strict.Match(http.MethodPost, "/Login") || !strict.Match(http.MethodGet, "/public/a/") {
+		t.Error("strict should match")
+	}
+}
@@ -0,0 +1,350 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 请求参数视图
+// 每个请求解析一次(请求内缓存), 所有 Resolve* 从中取值
+// 路由参数/查询串/表单直接读取fasthttp已解析的值, 仅JSON/multipart请求体解码后缓存
+//
+// 取值优先级: 路由参数 > 请求体 > 查询串
+//   - 路由参数: /order/:id
+//   - 请求体(POST/PUT/PATCH, 有请求体的DELETE): JSON / x-www-form-urlencoded / multipart/form-data
+//   - 查询串: ?a=1&a=2
+//
+// JSON 嵌套值以 "." 分隔取值(如 user.name), 数组为多值, 对象为JSON字符串
+
+// 请求体类型
+const (
+	bodyNone      = ""
+	bodyJson      = "json"
+	bodyForm      = "form"
+	bodyMultipart = "multipart"
+	bodyCodec     = "codec" // 其他已注册编解码(见RegisterCodec)
+)
+
+// RequestParams 请求参数
+type RequestParams struct {
+	c         *fiber.Ctx
+	bodyKind  string
+	json      map[string]any           // JSON/编解码请求体
+	codec     Codec                    // 请求体编解码(bodyCodec)
+	form      map[string][]string      // multipart表单值
+	multipart *multipart.Form          // multipart表单(含文件)
+	uploads   map[string][]*UploadFile // 流式上传文件(已保存到临时目录)
+	raw       []byte                   // JSON请求体
+	bodyErr   error                    // 请求体无法按参数名取值(编解码不支持解码为map)
+}
+
+// ResolveRequestParams 请求参数解析(请求内缓存)
+// 请求体无法按参数名取值(编解码不支持解码为map)时返回绑定错误, 此类请求体仅支持 ResolveBody 整体解码
+func ResolveRequestParams(c *fiber.Ctx) (*RequestParams, error) {
+	p, err := requestParams(c)
+	if err == nil && p.bodyErr != nil {
+		return nil, p.bodyErr
+	}
+	return p, err
+}
+
+// 请求参数(不检查请求体取值错误), 用于整体解码及路由参数/查询串
+func requestParams(c *fiber.Ctx) (*RequestParams, error) {
+	return ctxCached(c, ctxKeyParams, func() (*RequestParams, error) { return parseRequestParams(c) })
+}
+
+func parseRequestParams(c *fiber.Ctx) (*RequestParams, error) {
+	p := &RequestParams{c: c}
+	if !hasBody(c) {
+		return p, nil
+	}
+
+	// 上传请求不预先读取请求体(流式上传)
+	if isMultipart(c) {
+		p.bodyKind = bodyMultipart
+		if err := p.parseMultipart(); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	body := c.Body()
+	switch p.bodyKind = bodyKindOf(c, body); p.bodyKind {
+	case bodyJson:
+		if len(bytes.TrimSpace(body)) == 0 {
+			break
+		}
+		var v any
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&v); err != nil {
+			return nil, newBindErr("", sourceBody, err)
+		}
+		p.json, _ = v.(map[string]any)
+		p.raw = body
+	case bodyCodec:
+		// 解码为map按参数名取值
+		p.codec = CodecOf(string(c.Request().Header.ContentType()))
+		if err := p.codec.Unmarshal(body, &p.json); err != nil {
+			p.bodyErr = newBindErr("", sourceBody, err)
+		}
+	}
+	return p, nil
+}
+
+// 上传表单, 流式请求体(Config.Upload.Stream)边读边保存文件
+func (p *RequestParams) parseMultipart() error {
+	if p.c.Request().IsBodyStream() {
+		form, uploads, err := readMultipartStream(p.c)
+		if err != nil {
+			return err
+		}
+		p.form, p.uploads = form, uploads
+		return nil
+	}
+	form, err := p.c.MultipartForm()
+	if err != nil {
+		return newBindErr("", sourceBody, err)
+	}
+	p.form, p.multipart = form.Value, form
+	return nil
+}
+
+// 请求体类型, 无Content-Type时按内容识别JSON
+func bodyKindOf(c *fiber.Ctx, body []byte) string {
+	ct := strings.ToLower(string(c.Request().Header.ContentType()))
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	switch ct = strings.TrimSpace(ct); {
+	case ct == fiber.MIMEApplicationForm:
+		return bodyForm
+	case ct == fiber.MIMEMultipartForm:
+		return bodyMultipart
+	case strings.HasSuffix(ct, "/json") || strings.HasSuffix(ct, "+json"):
+		return bodyJson
+	case ct == "" && len(bytes.TrimSpace(body)) > 0 && bytes.TrimSpace(body)[0] == '{':
+		return bodyJson
+	case ct != "" && CodecOf(ct) != nil:
+		return bodyCodec
+	}
+	return bodyNone
+}
+
+// Get 参数值(多值时取第一个)
+func (p *RequestParams) Get(name string) string {
+	if vs, _ := p.Lookup(name); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Values 参数多值
+func (p *RequestParams) Values(name string) []string {
+	vs, _ := p.Lookup(name)
+	return vs
+}
+
+// Has 参数是否存在
+func (p *RequestParams) Has(name string) bool {
+	_, source := p.Lookup(name)
+	return source != ""
+}
+
+// Lookup 按优先级取值, 返回值及来源(path/body/query, 不存在为空)
+func (p *RequestParams) Lookup(name string) ([]string, string) {
+	if v := p.c.Params(name); v != "" {
+		return []string{v}, sourcePath
+	}
+	if vs, ok := p.bodyValues(name); ok {
+		return vs, sourceBody
+	}
+	if vs := p.queryValues(name); len(vs) > 0 {
+		return vs, sourceQuery
+	}
+	return nil, ""
+}
+
+// Path 路由参数
+func (p *RequestParams) Path(name string) string {
+	return p.c.Params(name)
+}
+
+// Query 查询参数
+func (p *RequestParams) Query(name string) string {
+	return string(p.c.Context().QueryArgs().Peek(name))
+}
+
+// Body 请求体参数
+func (p *RequestParams) Body(name string) string {
+	if vs, _ := p.bodyValues(name); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Map 合并参数(按优先级, 多值取第一个)
+func (p *RequestParams) Map() map[string]string {
+	m := make(map[string]string)
+	p.c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if _, ok := m[string(key)]; !ok {
+			m[string(key)] = string(value)
+		}
+	})
+	for k, v := range p.BodyMap() {
+		m[k] = v
+	}
+	for k, v := range p.c.AllParams() {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// BodyMap 请求体参数(多值取第一个)
+func (p *RequestParams) BodyMap() map[string]string {
+	m := make(map[string]string)
+	switch p.bodyKind {
+	case bodyJson, bodyCodec:
+		for k, v := range p.json {
+			if vs := toValues(v); len(vs) > 0 {
+				m[k] = vs[0]
+			}
+		}
+	case bodyForm:
+		p.c.Context().PostArgs().VisitAll(func(key, value []byte) {
+			if _, ok := m[string(key)]; !ok {
+				m[string(key)] = string(value)
+			}
+		})
+	case bodyMultipart:
+		for k, vs := range p.form {
+			if len(vs) > 0 {
+				m[k] = vs[0]
+			}
+		}
+	}
+	return m
+}
+
+func (p *RequestParams) queryValues(name string) []string {
+	return peekMulti(p.c.Context().QueryArgs(), name)
+}
+
+func (p *RequestParams) bodyValues(name string) ([]string, bool) {
+	switch p.bodyKind {
+	case bodyJson, bodyCodec:
+		if v, ok := p.lookupJson(name); ok {
+			return toValues(v), true
+		}
+	case bodyForm:
+		if args := p.c.Context().PostArgs(); args.Has(name) {
+			return peekMulti(args, name), true
+		}
+	case bodyMultipart:
+		if vs, ok := p.form[name]; ok {
+			return vs, true
+		}
+	}
+	return nil, false
+}
+
+func peekMulti(args *fasthttp.Args, name string) []string {
+	var vs []string
+	for _, it := range args.PeekMulti(name) {
+		vs = append(vs, string(it))
+	}
+	return vs
+}
+
+// JSON取值, 支持嵌套(a.b.c)
+func (p *RequestParams) lookupJson(name string) (any, bool) {
+	if v, ok := p.json[name]; ok {
+		return v, true
+	}
+	if !strings.Contains(name, ".") {
+		return nil, false
+	}
+	var cur any = p.json
+	for _, key := range strings.Split(name, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// 转字符串多值: 标量数组为多值, 对象/嵌套数组为JSON字符串
+func toValues(v any) []string {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		vs := make([]string, 0, len(t))
+		for _, it := range t {
+			s, ok := scalarString(it)
+			if !ok {
+				js, _ := json.Marshal(t)
+				return []string{string(js)}
+			}
+			vs = append(vs, s)
+		}
+		return vs
+	}
+	if s, ok := scalarString(v); ok {
+		return []string{s}
+	}
+	js, _ := json.Marshal(v)
+	return []string{string(js)}
+}
+
+func scalarString(v any) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case json.Number:
+		return t.String(), true
+	case bool:
+		return strconv.FormatBool(t), true
+	}
+	// 其他编解码的数值
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// 解析到对象: JSON/已注册编解码请求体直接解码, 表单/查询串按 form/query 标签
+func (p *RequestParams) decode(dist any) error {
+	c := p.c
+	switch {
+	case p.bodyKind == bodyMultipart && p.multipart == nil:
+		return errors.New("multipart stream body not support, use param")
+	case p.bodyKind == bodyJson && len(p.raw) > 0:
+		return json.Unmarshal(p.raw, dist)
+	case p.bodyKind == bodyForm || p.bodyKind == bodyMultipart:
+		return c.BodyParser(dist)
+	case p.bodyKind == bodyCodec:
+		return p.codec.Unmarshal(c.Body(), dist)
+	case hasBody(c) && len(c.Body()) > 0:
+		return fiber.ErrUnprocessableEntity
+	}
+	return c.QueryParser(dist)
+}
@@ -0,0 +1,132 @@
+package web
+
+import (
+	"bytes"
+	"github.com/elancom/go-util/lang"
+	"github.com/elancom/go-util/param"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRequestParams(t *testing.T) {
+	server := newBindServer()
+	handle := func(page *lang.Page, params *param.Params) error {
+		return lang.NewOk(strings.Join([]string{
+			params.Get("id"), params.Get("name"), params.Get("vip"), params.Get("q"), params.Get("tags"),
+			strconv.Itoa(page.GetPage()), strconv.Itoa(page.GetRows()),
+		}, ","))
+	}
+	server.App.All("/user/:id", UsePageParams(handle))
+	server.App.Post("/nested", Bind2(func(city string, id int64) error {
+		return lang.NewOk(city + ":" + string(rune('0'+id)))
+	}, ResolveParam("addr.city"), ResolveInt[int64]("id")))
+	server.App.Get("/path/:id", UsePathVar(func(params *param.Params) error {
+		return lang.NewOk(params.Get("id"))
+	}))
+
+	test := func(request *http.Request, want string) {
+		if msg := testMsg(t, server, request); msg.Data != want {
+			t.Fatal(request.Method, request.URL, msg)
+		}
+	}
+
+	// JSON 非字符串值, 路由参数优先, 查询串兜底
+	request, _ := http.NewRequest(http.MethodPost, "/user/7?q=x&id=1&name=jerry",
+		strings.NewReader(`{"id":8,"name":"tom","vip":true,"tags":["a","b"],"page":2,"rows":5}`))
+	request.Header.Set("Content-Type", "application/json")
+	test(request, "7,tom,true,x,a,2,5")
+
+	request, _ = http.NewRequest(http.MethodGet, "/user/7?name=tom&page=3", nil)
+	test(request, "7,tom,,,,3,10")
+
+	request, _ = http.NewRequest(http.MethodPut, "/user/7", strings.NewReader("name=tom&tags=a&tags=b&rows=9"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	test(request, "7,tom,,,a,1,9")
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("name", "tom")
+	_ = writer.WriteField("page", "4")
+	_ = writer.Close()
+	request, _ = http.NewRequest(http.MethodPatch, "/user/7", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	test(request, "7,tom,,,,4,10")
+
+	request, _ = http.NewRequest(http.MethodPost, "/nested", strings.NewReader(`{"id":3,"addr":{"city":"sz"}}`))
+	request.Header.Set("Content-Type", "application/json")
+	test(request, "sz:3")
+
+	request, _ = http.NewRequest(http.MethodGet, "/path/5", nil)
+	test(request, "5")
+
+	request, _ = http.NewRequest(http.MethodPost, "/nested", strings.NewReader(`{"id":`))
+	request.Header.Set("Content-Type", "application/json")
+	if msg := testMsg(t, server, request); msg.Code != CodeBind || msg.Data.(map[string]any)["source"] != "body" {
+		t.Fatal(msg)
+	}
+}
+
+func TestRequestParamsLocals(t *testing.T) {
+	server := newBindServer()
+	server.App.Get("/locals", func(c *fiber.Ctx) error {
+		c.Locals("params", "x")
+		params, err := ResolveRequestParams(c)
+		if err != nil {
+			return err
+		}
+		local, _ := c.Locals("params").(string)
+		return lang.NewOk(params.Get("name") + ":" + local)
+	})
+	request, _ := http.NewRequest(http.MethodGet, "/locals?name=tom", nil)
+	if msg := testMsg(t, server, request); msg.Data != "tom:x" {
+		t.Fatal(msg)
+	}
+}
+
+// 请求内缓存对比: UsePageParams 与每个解析器各自解析请求体
+func BenchmarkUsePageParams(b *testing.B) {
+	handle := func(page *lang.Page, params *param.Params) error { return nil }
+	uncached := func(r Resolver[*param.Params]) Resolver[*param.Params] {
+		return func(c *fiber.Ctx) (*param.Params, error) {
+			c.Context().ResetUserValues()
+			return r(c)
+		}
+	}
+	uncachedPage := func(c *fiber.Ctx) (*lang.Page, error) {
+		page, err := ResolvePage(c)
+		c.Context().ResetUserValues()
+		return page, err
+	}
+	handlers := map[string]fiber.Handler{
+		"cached":   UsePageParams(handle),
+		"uncached": Bind2(handle, uncachedPage, uncached(ResolveParams)),
+	}
+
+	for _, name := range []string{"cached", "uncached"} {
+		app := fiber.New()
+		app.Post("/user/:id", handlers[name])
+		serve := app.Handler()
+		b.Run(name, func(b *testing.B) {
+			ctx := new(fasthttp.RequestCtx)
+			ctx.Request.Header.SetMethod(http.MethodPost)
+			ctx.Request.Header.SetContentType(fiber.MIMEApplicationJSON)
+			ctx.Request.SetRequestURI("/user/7?q=x")
+			ctx.Request.SetBodyString(`{"page":2,"rows":20,"name":"tom","vip":true,"tags":["a","b"],"addr":{"city":"sz"}}`)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ctx.ResetUserValues()
+				serve(ctx)
+				if ctx.Response.StatusCode() != http.StatusOK {
+					b.Fatal(string(ctx.Response.Body()))
+				}
+			}
+		})
+	}
+}
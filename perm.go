@@ -0,0 +1,118 @@
+package web
+
+import (
+	"github.com/elancom/go-util/lang"
+	"github.com/elancom/go-util/str"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PermissionResolver 角色/权限解析(按用户ID), 结果与令牌携带的角色/权限合并
+type PermissionResolver interface {
+	Roles(id int64) ([]string, error)
+	Perms(id int64) ([]string, error)
+}
+
+// 本次请求已解析的角色/权限
+type grants struct {
+	roles []string
+	perms []string
+}
+
+// 取当前用户角色/权限(令牌携带 + PermissionResolver解析)
+func resolveGrants(c *fiber.Ctx) (*grants, error) {
+	return ctxCached(c, ctxKeyGrants, func() (*grants, error) { return loadGrants(c) })
+}
+
+func loadGrants(c *fiber.Ctx) (*grants, error) {
+	principal, err := ResolveUser(c)
+	if err != nil {
+		return nil, lang.NotAuthorized
+	}
+
+	g := &grants{roles: principal.Roles, perms: principal.Perms}
+	if s := serverOf(c); s != nil && s.config.PermissionResolver != nil {
+		resolver := s.config.PermissionResolver
+		roles, err := resolver.Roles(principal.Id)
+		if err != nil {
+			return nil, err
+		}
+		perms, err := resolver.Perms(principal.Id)
+		if err != nil {
+			return nil, err
+		}
+		g.roles = append(append([]string{}, g.roles...), roles...)
+		g.perms = append(append([]string{}, g.perms...), perms...)
+	}
+	return g, nil
+}
+
+// HasRole 是否拥有任一角色
+func HasRole(c *fiber.Ctx, roles ...string) (bool, error) {
+	g, err := resolveGrants(c)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		for _, it := range g.roles {
+			if it == role {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// HasPerm 是否拥有全部权限
+// 权限支持通配: "*" 全部, "order:*" order下全部
+func HasPerm(c *fiber.Ctx, perms ...string) (bool, error) {
+	g, err := resolveGrants(c)
+	if err != nil {
+		return false, err
+	}
+	for _, perm := range perms {
+		if !matchPerm(g.perms, perm) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchPerm(granted []string, perm string) bool {
+	for _, it := range granted {
+		if it == perm || it == "*" {
+			return true
+		}
+		if str.HasSuffix(it, ":*") && str.HasPrefix(perm, it[:len(it)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole 路由守卫: 需要任一角色
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ok, err := HasRole(c, roles...)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return lang.NotAuthorized
+		}
+		return c.Next()
+	}
+}
+
+// RequirePerm 路由守卫: 需要全部权限
+func RequirePerm(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ok, err := HasPerm(c, perms...)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return lang.NotAuthorized
+		}
+		return c.Next()
+	}
+}
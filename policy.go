@@ -0,0 +1,87 @@
+package web
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+// Policy 安全策略
+type Policy struct {
+	Auth    bool   // TK认证
+	Sign    bool   // 签名认证
+	Enc     bool   // 加密(请求x-enc时解密, 响应加密)
+	SignKey string // 签名秘钥(无用户时使用, 如webhook)
+}
+
+type policyRule struct {
+	matcher *PathMatcher
+	policy  Policy
+}
+
+// Secure 为路由组设置安全策略, 替代全局 AuthEnable/SignEnable/EncEnable 及忽略地址
+// rules 为组内相对路径规则(规则见PathMatcher), 默认组内全部; 后设置的策略优先
+//
+//	s.Secure(app.Group("/pay"), Policy{Auth: true, Sign: true, Enc: true})
+//	s.Secure(app, Policy{Sign: true, SignKey: key}, "POST /hook/**")
+func (s *Server) Secure(router fiber.Router, policy Policy, rules ...string) fiber.Router {
+	prefix := ""
+	if g, ok := router.(*fiber.Group); ok {
+		prefix = strings.TrimRight(g.Prefix, "/")
+	}
+	if len(rules) == 0 {
+		rules = []string{"/**"}
+	}
+
+	matcher := s.routeMatcher(NewPathMatcher())
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		method, exact := "", ""
+		if i := strings.IndexByte(rule, ' '); i > 0 {
+			method, rule = rule[:i+1], strings.TrimSpace(rule[i+1:])
+		}
+		if strings.HasPrefix(rule, "=") {
+			exact, rule = "=", rule[1:]
+		}
+		matcher.Add(method + exact + prefix + "/" + strings.TrimLeft(rule, "/"))
+	}
+	s.policies = append(s.policies, policyRule{matcher: matcher, policy: policy})
+	return router
+}
+
+// 同fiber路由的路径匹配方式(大小写/末尾 /)
+func (s *Server) routeMatcher(m *PathMatcher) *PathMatcher {
+	if s.App != nil {
+		config := s.App.Config()
+		m.CaseSensitive, m.StrictRouting = config.CaseSensitive, config.StrictRouting
+	}
+	return m
+}
+
+// 当前请求安全策略
+func (s *Server) policyOf(c *fiber.Ctx) *Policy {
+	policy, _ := ctxCached(c, ctxKeyPolicy, func() (*Policy, error) { return s.matchPolicy(c), nil })
+	return policy
+}
+
+func (s *Server) matchPolicy(c *fiber.Ctx) *Policy {
+	method, path := c.Method(), c.Path()
+	var policy *Policy
+	for i := len(s.policies) - 1; i >= 0; i-- {
+		if s.policies[i].matcher.Match(method, path) {
+			p := s.policies[i].policy
+			policy = &p
+			break
+		}
+	}
+
+	// 全局配置
+	if policy == nil {
+		ignored := s.login.Match(method, path) || s.ignore.Match(method, path)
+		policy = &Policy{
+			Auth: s.config.AuthEnable && !ignored,
+			Sign: s.config.AuthEnable && s.config.SignEnable && !ignored,
+			Enc:  s.config.EncEnable && !s.login.Match(method, path),
+		}
+	}
+	return policy
+}
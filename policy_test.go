@@ -0,0 +1,67 @@
+package web
+
+import (
+	"github.com/elancom/go-util/lang"
+	"github.com/elancom/go-util/sign"
+	"net/http"
+	"testing"
+)
+
+func TestSecure(t *testing.T) {
+	server := NewServer(Config{AuthEnable: true, SignEnable: true})
+	server.Init()
+	ok := Use(func() error { return lang.NewOk() })
+
+	server.Secure(server.App, Policy{}, "GET /public/**")
+	hook := server.Secure(server.App.Group("/hook"), Policy{Sign: true, SignKey: "hook-key"})
+	hook.Get("/notify", ok)
+	server.App.Get("/public/info", ok)
+	server.App.Get("/private", ok)
+
+	request := func(path string, header ...string) *http.Request {
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			request.Header.Set(header[i], header[i+1])
+		}
+		return request
+	}
+
+	if msg := testMsg(t, server, request("/public/info")); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/private")); msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/hook/notify?a=1")); msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/hook/notify?a=1", "x-sign", sign.Str("a=1", "hook-key"))); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+}
+
+func TestSecureRouteMatch(t *testing.T) {
+	server := NewServer(Config{})
+	server.Init()
+	called := 0
+	ok := Use(func() error { called++; return lang.NewOk() })
+
+	pay := server.Secure(server.App.Group("/pay"), Policy{Auth: true})
+	pay.Get("/x", ok)
+	server.Secure(server.App, Policy{Auth: true}, "GET /admin/**")
+	server.App.Get("/admin/x", ok)
+
+	for _, path := range []string{"/PAY/x", "/pay/x/", "/Admin/x"} {
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		if msg := testMsg(t, server, request); msg.IsOk() {
+			t.Fatal(path, msg)
+		}
+	}
+	request, _ := http.NewRequest(http.MethodHead, "/admin/x", nil)
+	if _, err := server.App.Test(request); err != nil {
+		t.Fatal(err)
+	}
+	if called != 0 {
+		t.Fatal("policy bypassed", called)
+	}
+}
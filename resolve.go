@@ -0,0 +1,215 @@
+package web
+
+import (
+	"errors"
+	"github.com/elancom/go-util/lang"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 类型参数解析
+// 取值见 RequestParams(路由参数 > 请求体 > 查询串); 参数缺失返回 ParamMissing
+// 默认必填, 可选参数使用 Optional 包装
+//
+//	app.Get("/list", web.Bind2(handle, web.ResolveTime("from", "2006-01-02"), web.Optional(web.ResolveBool("all"), false)))
+
+// ParamMissing 参数缺失
+var ParamMissing = errors.New("missing")
+
+// Optional 可选参数, 缺失时返回默认值(无默认值时为零值)
+func Optional[T any](r Resolver[T], def ...T) Resolver[T] {
+	return func(c *fiber.Ctx) (T, error) {
+		v, err := r(c)
+		if errors.Is(err, ParamMissing) {
+			var d T
+			if len(def) > 0 {
+				d = def[0]
+			}
+			return d, nil
+		}
+		return v, err
+	}
+}
+
+// 参数解析器: 取值 -> 缺失检查 -> 转换
+func resolveValue[T any](name string, parse func(s string) (T, error)) Resolver[T] {
+	return func(c *fiber.Ctx) (T, error) {
+		var t T
+		params, err := ResolveRequestParams(c)
+		if err != nil {
+			return t, toBindErr(err, 0).withName(name)
+		}
+		vs, source := params.Lookup(name)
+		if source == "" {
+			source = sourceOf(c)
+		}
+		s := ""
+		if len(vs) > 0 {
+			s = strings.TrimSpace(vs[0])
+		}
+		if s == "" {
+			return t, newBindErr(name, source, ParamMissing)
+		}
+		if t, err = parse(s); err != nil {
+			return t, newBindErr(name, source, err)
+		}
+		return t, nil
+	}
+}
+
+// ResolveBool 布尔参数(1/0/true/false)
+func ResolveBool(name string) Resolver[bool] {
+	return resolveValue(name, strconv.ParseBool)
+}
+
+// ResolveFloat 浮点参数
+func ResolveFloat(name string) Resolver[float64] {
+	return resolveValue(name, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+}
+
+// ResolveTime 时间参数, 按layout依次尝试(默认RFC3339), 无时区时为本地时间
+func ResolveTime(name string, layout ...string) Resolver[time.Time] {
+	return resolveValue(name, TimeParser(layout...))
+}
+
+// TimeParser 时间解析, 按layout依次尝试(默认RFC3339), 无时区时为本地时间
+func TimeParser(layout ...string) func(s string) (time.Time, error) {
+	if len(layout) == 0 {
+		layout = []string{time.RFC3339}
+	}
+	return func(s string) (t time.Time, err error) {
+		for _, it := range layout {
+			if t, err = time.ParseInLocation(it, s, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return t, err
+	}
+}
+
+// ResolveDuration 时长参数(如 1h30m)
+func ResolveDuration(name string) Resolver[time.Duration] {
+	return resolveValue(name, time.ParseDuration)
+}
+
+// ResolveUUID UUID参数
+func ResolveUUID(name string) Resolver[uuid.UUID] {
+	return resolveValue(name, uuid.Parse)
+}
+
+// ResolveEnum 枚举参数, 值须在allowed中
+func ResolveEnum(name string, allowed ...string) Resolver[string] {
+	return resolveValue(name, func(s string) (string, error) {
+		for _, it := range allowed {
+			if it == s {
+				return s, nil
+			}
+		}
+		return "", lang.NewErr("must be one of " + strings.Join(allowed, ","))
+	})
+}
+
+// ResolveSlice 多值参数, 支持 ?id=1&id=2 及 ?id=1,2
+// parse 为单值解析, 默认支持基本类型及 uuid.UUID/time.Time(RFC3339)/time.Duration
+//
+//	web.ResolveSlice("day", web.TimeParser("2006-01-02"))
+func ResolveSlice[T any](name string, parse ...func(s string) (T, error)) Resolver[[]T] {
+	p := valueParser[T]()
+	if len(parse) > 0 {
+		p = parse[0]
+	}
+	return func(c *fiber.Ctx) ([]T, error) {
+		params, err := ResolveRequestParams(c)
+		if err != nil {
+			return nil, toBindErr(err, 0).withName(name)
+		}
+		raw, source := params.Lookup(name)
+		if source == "" {
+			source = sourceOf(c)
+		}
+		values := splitValues(raw)
+		if len(values) == 0 {
+			return nil, newBindErr(name, source, ParamMissing)
+		}
+		dist := make([]T, len(values))
+		for i, it := range values {
+			if dist[i], err = p(it); err != nil {
+				return nil, newBindErr(name, source, err)
+			}
+		}
+		return dist, nil
+	}
+}
+
+// 默认单值解析: 同 ResolveUUID/ResolveTime/ResolveDuration, 其他按基本类型转换
+func valueParser[T any]() func(s string) (T, error) {
+	var parse any
+	switch any(*new(T)).(type) {
+	case uuid.UUID:
+		parse = uuid.Parse
+	case time.Time:
+		parse = TimeParser()
+	case time.Duration:
+		parse = time.ParseDuration
+	}
+	if p, ok := parse.(func(s string) (T, error)); ok {
+		return p
+	}
+	return func(s string) (T, error) {
+		var t T
+		err := setValue(reflect.ValueOf(&t).Elem(), s)
+		return t, err
+	}
+}
+
+// 多值按逗号展开, 忽略空值
+func splitValues(raw []string) []string {
+	values := make([]string, 0, len(raw))
+	for _, it := range raw {
+		for _, s := range strings.Split(it, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// UseBool 注入布尔参数
+func UseBool(handle HandleP1[bool], name string) fiber.Handler {
+	return Bind1(handle, ResolveBool(name))
+}
+
+// UseFloat 注入浮点参数
+func UseFloat(handle HandleP1[float64], name string) fiber.Handler {
+	return Bind1(handle, ResolveFloat(name))
+}
+
+// UseTime 注入时间参数
+func UseTime(handle HandleP1[time.Time], name string, layout ...string) fiber.Handler {
+	return Bind1(handle, ResolveTime(name, layout...))
+}
+
+// UseDuration 注入时长参数
+func UseDuration(handle HandleP1[time.Duration], name string) fiber.Handler {
+	return Bind1(handle, ResolveDuration(name))
+}
+
+// UseUUID 注入UUID参数
+func UseUUID(handle HandleP1[uuid.UUID], name string) fiber.Handler {
+	return Bind1(handle, ResolveUUID(name))
+}
+
+// UseEnum 注入枚举参数
+func UseEnum(handle HandleP1[string], name string, allowed ...string) fiber.Handler {
+	return Bind1(handle, ResolveEnum(name, allowed...))
+}
+
+// UseSlice 注入多值参数
+func UseSlice[T any](handle HandleP1[[]T], name string) fiber.Handler {
+	return Bind1(handle, ResolveSlice[T](name))
+}
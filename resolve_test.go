@@ -0,0 +1,67 @@
+package web
+
+import (
+	"fmt"
+	"github.com/elancom/go-util/lang"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveTyped(t *testing.T) {
+	server := newBindServer()
+	server.App.Get("/q", BindN(func(a Args) error {
+		return lang.NewOk(fmt.Sprintln(Get[bool](a, 0), Get[float64](a, 1), Get[time.Time](a, 2).Format("2006-01-02"),
+			Get[time.Duration](a, 3), Get[uuid.UUID](a, 4), Get[string](a, 5), Get[[]int64](a, 6)))
+	}, R(ResolveBool("b")), R(ResolveFloat("f")), R(ResolveTime("t", "2006-01-02")), R(ResolveDuration("d")),
+		R(ResolveUUID("u")), R(ResolveEnum("e", "asc", "desc")), R(ResolveSlice[int64]("id"))))
+	server.App.Get("/opt/:sort?", Bind2(func(all bool, sort string) error {
+		return lang.NewOk(fmt.Sprint(all, " ", sort))
+	}, Optional(ResolveBool("all"), true), Optional(ResolveEnum("sort", "asc", "desc"))))
+	server.App.Get("/slices", Bind2(func(ids []uuid.UUID, days []time.Time) error {
+		return lang.NewOk(fmt.Sprint(len(ids), " ", days[1].Format("01-02")))
+	}, ResolveSlice[uuid.UUID]("id"), ResolveSlice("day", TimeParser("2006-01-02"))))
+	server.App.Post("/ids", UseSlice(func(ids []string) error {
+		return lang.NewOk(strings.Join(ids, "|"))
+	}, "id"))
+
+	id := uuid.NewString()
+	request, _ := http.NewRequest(http.MethodGet, "/q?b=1&f=1.5&t=2024-05-01&d=1h30m&u="+id+"&e=desc&id=1&id=2,3", nil)
+	if msg := testMsg(t, server, request); msg.Data != "true 1.5 2024-05-01 1h30m0s "+id+" desc [1 2 3]\n" {
+		t.Fatal(msg)
+	}
+
+	for query, name := range map[string]string{"b=1": "f", "b=x": "b", "b=1&f=1&t=2024&d=1h&u=x&e=asc&id=1": "t"} {
+		request, _ = http.NewRequest(http.MethodGet, "/q?"+query, nil)
+		msg := testMsg(t, server, request)
+		if data, _ := msg.Data.(map[string]any); msg.Code != CodeBind || data["name"] != name {
+			t.Fatal(query, msg)
+		}
+	}
+
+	request, _ = http.NewRequest(http.MethodGet, "/opt", nil)
+	if msg := testMsg(t, server, request); msg.Data != "true " {
+		t.Fatal(msg)
+	}
+	request, _ = http.NewRequest(http.MethodGet, "/opt/desc?all=false", nil)
+	if msg := testMsg(t, server, request); msg.Data != "false desc" {
+		t.Fatal(msg)
+	}
+	request, _ = http.NewRequest(http.MethodGet, "/opt/up", nil)
+	if msg := testMsg(t, server, request); msg.Code != CodeBind {
+		t.Fatal(msg)
+	}
+
+	request, _ = http.NewRequest(http.MethodGet, "/slices?id="+id+","+uuid.NewString()+"&day=2024-05-01&day=2024-05-02", nil)
+	if msg := testMsg(t, server, request); msg.Data != "2 05-02" {
+		t.Fatal(msg)
+	}
+
+	request, _ = http.NewRequest(http.MethodPost, "/ids", strings.NewReader("id=a&id=b,c"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if msg := testMsg(t, server, request); msg.Data != "a|b|c" {
+		t.Fatal(msg)
+	}
+}
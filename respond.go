@@ -0,0 +1,158 @@
+package web
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"net/http"
+)
+
+// 非消息响应, 处理器返回后由消息处理中间件直接输出
+//
+//	app.Get("/export", web.Use(func() error { return web.File("/data/report.xlsx", "report.xlsx") }))
+//	app.Get("/home", web.Use(func() error { return web.Redirect("/index.html") }))
+//
+// 加密策略(Policy.Enc)下:
+//   - Bytes/HTML: 加密内容, 同消息响应(x-enc)
+//   - Redirect: 原样输出(无响应体)
+//   - File/Stream: 拒绝(无法加密), 返回错误消息
+
+// EncMode 响应加密方式
+type EncMode int
+
+const (
+	EncEncrypt EncMode = iota // 加密内容(需实现 Payload() []byte)
+	EncSkip                   // 原样输出
+	EncReject                 // 拒绝输出
+)
+
+// Responder 自定义响应
+type Responder interface {
+	error
+	Respond(c *fiber.Ctx) error // 输出响应
+	EncMode() EncMode           // 加密策略下的处理方式
+}
+
+// 可加密/签名的响应内容
+type payloader interface {
+	Payload() []byte
+}
+
+// FileResponse 文件响应
+type FileResponse struct {
+	Path string
+	Name string // 下载文件名("":直接输出)
+}
+
+// File 文件响应, name 非空时作为附件下载
+func File(path string, name ...string) *FileResponse {
+	r := &FileResponse{Path: path}
+	if len(name) > 0 {
+		r.Name = name[0]
+	}
+	return r
+}
+
+func (r *FileResponse) Error() string { return "file: " + r.Path }
+
+func (r *FileResponse) EncMode() EncMode { return EncReject }
+
+func (r *FileResponse) Respond(c *fiber.Ctx) error {
+	if r.Name != "" {
+		return c.Download(r.Path, r.Name)
+	}
+	return c.SendFile(r.Path)
+}
+
+// BytesResponse 二进制响应
+type BytesResponse struct {
+	Data        []byte
+	ContentType string
+}
+
+// Bytes 二进制响应(默认 application/octet-stream)
+func Bytes(data []byte, contentType ...string) *BytesResponse {
+	r := &BytesResponse{Data: data, ContentType: fiber.MIMEOctetStream}
+	if len(contentType) > 0 {
+		r.ContentType = contentType[0]
+	}
+	return r
+}
+
+func (r *BytesResponse) Error() string { return "bytes: " + r.ContentType }
+
+func (r *BytesResponse) EncMode() EncMode { return EncEncrypt }
+
+func (r *BytesResponse) Payload() []byte { return r.Data }
+
+func (r *BytesResponse) Respond(c *fiber.Ctx) error {
+	c.Response().Header.SetContentType(r.ContentType)
+	return c.Send(r.Data)
+}
+
+// StreamResponse 流响应, Reader 实现 io.Closer 时输出后关闭
+type StreamResponse struct {
+	Reader      io.Reader
+	ContentType string
+}
+
+// Stream 流响应(默认 application/octet-stream)
+func Stream(reader io.Reader, contentType ...string) *StreamResponse {
+	r := &StreamResponse{Reader: reader, ContentType: fiber.MIMEOctetStream}
+	if len(contentType) > 0 {
+		r.ContentType = contentType[0]
+	}
+	return r
+}
+
+func (r *StreamResponse) Error() string { return "stream: " + r.ContentType }
+
+func (r *StreamResponse) EncMode() EncMode { return EncReject }
+
+func (r *StreamResponse) Respond(c *fiber.Ctx) error {
+	c.Response().Header.SetContentType(r.ContentType)
+	return c.SendStream(r.Reader)
+}
+
+// RedirectResponse 重定向响应
+type RedirectResponse struct {
+	Location string
+	Status   int
+}
+
+// Redirect 重定向(默认302)
+func Redirect(location string, status ...int) *RedirectResponse {
+	r := &RedirectResponse{Location: location, Status: http.StatusFound}
+	if len(status) > 0 {
+		r.Status = status[0]
+	}
+	return r
+}
+
+func (r *RedirectResponse) Error() string { return "redirect: " + r.Location }
+
+func (r *RedirectResponse) EncMode() EncMode { return EncSkip }
+
+func (r *RedirectResponse) Respond(c *fiber.Ctx) error {
+	return c.Redirect(r.Location, r.Status)
+}
+
+// HTMLResponse HTML响应
+type HTMLResponse struct {
+	Html string
+}
+
+// HTML HTML响应
+func HTML(html string) *HTMLResponse {
+	return &HTMLResponse{Html: html}
+}
+
+func (r *HTMLResponse) Error() string { return "html" }
+
+func (r *HTMLResponse) EncMode() EncMode { return EncEncrypt }
+
+func (r *HTMLResponse) Payload() []byte { return []byte(r.Html) }
+
+func (r *HTMLResponse) Respond(c *fiber.Ctx) error {
+	c.Response().Header.SetContentType(fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(r.Html)
+}
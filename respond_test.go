@@ -0,0 +1,82 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testBody(t *testing.T, server *Server, request *http.Request) (*http.Response, string) {
+	resp, err := server.App.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	return resp, string(b)
+}
+
+func TestRespond(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(Config{AuthEnable: true, EncEnable: true})
+	server.Init()
+	server.Secure(server.App, Policy{}, "/pub/**")
+	for _, prefix := range []string{"/pub", "/enc"} {
+		server.App.Get(prefix+"/bytes", Use(func() error { return Bytes([]byte{1, 2, 3}) }))
+		server.App.Get(prefix+"/html", Use(func() error { return HTML("<p>hi</p>") }))
+		server.App.Get(prefix+"/redirect", Use(func() error { return Redirect("/home", http.StatusMovedPermanently) }))
+		server.App.Get(prefix+"/stream", Use(func() error { return Stream(strings.NewReader("stream"), "text/plain") }))
+		server.App.Get(prefix+"/file", Use(func() error { return File(path, "b.txt") }))
+		server.App.Get(prefix+"/missing", Use(func() error { return File(path + ".x") }))
+		server.App.Get(prefix+"/missing-download", Use(func() error { return File(path+".x", "b.txt") }))
+	}
+	token, _ := server.MakeToken(1, "tom", testSecret)
+
+	get := func(url string) (*http.Response, string) {
+		request, _ := http.NewRequest(http.MethodGet, url, nil)
+		request.Header.Set("x-token", token)
+		request.Header.Set("x-enc", EncV2)
+		return testBody(t, server, request)
+	}
+
+	// 不加密
+	if resp, body := get("/pub/bytes"); body != "\x01\x02\x03" || resp.Header.Get("Content-Type") != "application/octet-stream" {
+		t.Fatal(resp, body)
+	}
+	if resp, body := get("/pub/html"); body != "<p>hi</p>" || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatal(resp, body)
+	}
+	if _, body := get("/pub/stream"); body != "stream" {
+		t.Fatal(body)
+	}
+	if resp, body := get("/pub/file"); body != "file" || !strings.Contains(resp.Header.Get("Content-Disposition"), "b.txt") {
+		t.Fatal(resp, body)
+	}
+	if _, body := get("/pub/missing"); !strings.Contains(body, `"code":400`) || strings.Contains(body, path) {
+		t.Fatal(body)
+	}
+	if resp, body := get("/pub/missing-download"); resp.Header.Get("Content-Disposition") != "" || strings.Contains(body, path) {
+		t.Fatal(resp, body)
+	}
+
+	// 加密策略: Bytes/HTML加密, 重定向原样, 文件/流拒绝
+	resp, body := get("/enc/bytes")
+	if plain, err := DecryptPayload(resp.Header.Get("x-enc"), body, testSecret, nil); err != nil || !bytes.Equal(plain, []byte{1, 2, 3}) {
+		t.Fatal(body, err)
+	}
+	if resp, _ = get("/enc/redirect"); resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/home" {
+		t.Fatal(resp)
+	}
+	for _, url := range []string{"/enc/stream", "/enc/file"} {
+		if resp, body = get(url); resp.Header.Get("x-enc") != "" || body != `{"code":400,"msg":"response enc not support"}` {
+			t.Fatal(url, body)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+
+// CodeTokenRevoked 令牌已吊销消息码
+const CodeTokenRevoked = 401
+
+// TokenRevoked 令牌已吊销
+var TokenRevoked = errors.New("TokenRevoked")
+
+// RevocationStore 令牌吊销存储
+type RevocationStore interface {
+	// RevokeKey 吊销单个令牌(UserPrincipal.Key), ttl<=0永久
+	RevokeKey(key string, ttl time.Duration) error
+	// RevokeUser 吊销用户在before之前签发的全部令牌, ttl<=0永久
+	RevokeUser(id int64, before time.Time, ttl time.Duration) error
+	// IsRevoked 是否已吊销
+	IsRevoked(principal *UserPrincipal) (bool, error)
+}
+
+// NewMemoryRevocationStore 内存吊销存储(单机)
+func NewMemoryRevocationStore() RevocationStore {
+	m := new(memoryRevocationStore)
+	m.keys = newTtlMap[string, struct{}]()
+	m.users = newTtlMap[int64, time.Time]()
+	return m
+}
+
+type memoryRevocationStore struct {
+	keys  *ttlMap[string, struct{}]
+	users *ttlMap[int64, time.Time]
+}
+
+func (m *memoryRevocationStore) RevokeKey(key string, ttl time.Duration) error {
+	m.keys.put(key, struct{}{}, ttl, time.Now())
+	return nil
+}
+
+func (m *memoryRevocationStore) RevokeUser(id int64, before time.Time, ttl time.Duration) error {
+	now := time.Now()
+	if last, ok := m.users.get(id, now); ok && last.After(before) {
+		before = last
+	}
+	m.users.put(id, before, ttl, now)
+	return nil
+}
+
+func (m *memoryRevocationStore) IsRevoked(principal *UserPrincipal) (bool, error) {
+	now := time.Now()
+	if _, ok := m.keys.get(principal.Key, now); ok {
+		return true, nil
+	}
+	if before, ok := m.users.get(principal.Id, now); ok {
+		return time.UnixMilli(principal.Timestamp).Before(before), nil
+	}
+	return false, nil
+}
+
+// 吊销记录保留时长(令牌过期后无需保留)
+func (s *Server) revokeTTL() time.Duration {
+	if s.config.TokenTTL <= 0 {
+		return 0
+	}
+	skew := s.config.TokenSkew
+	if skew == 0 {
+		skew = defaultTokenSkew
+	}
+	return s.config.TokenTTL + skew
+}
+
+func (s *Server) revocationStore() (RevocationStore, error) {
+	if s.config.RevocationStore == nil {
+		return nil, errors.New("revocation store not configured")
+	}
+	return s.config.RevocationStore, nil
+}
+
+// 吊销检查
+func (s *Server) checkRevoked(principal *UserPrincipal) error {
+	if s.config.RevocationStore == nil {
+		return nil
+	}
+	revoked, err := s.config.RevocationStore.IsRevoked(principal)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return TokenRevoked
+	}
+	return nil
+}
+
+// RevokePrincipal 吊销单个令牌
+func (s *Server) RevokePrincipal(principal *UserPrincipal) error {
+	store, err := s.revocationStore()
+	if err != nil {
+		return err
+	}
+	return store.RevokeKey(principal.Key, s.revokeTTL())
+}
+
+// RevokeToken 吊销单个令牌
+func (s *Server) RevokeToken(token string) error {
+	principal, err := s.GetUserPrincipal(token)
+	if err != nil {
+		return err
+	}
+	return s.RevokePrincipal(principal)
+}
+
+// RevokeUser 吊销用户在before之前签发的全部令牌
+func (s *Server) RevokeUser(id int64, before time.Time) error {
+	store, err := s.revocationStore()
+	if err != nil {
+		return err
+	}
+	return store.RevokeUser(id, before, s.revokeTTL())
+}
+
+// Logout 注销当前令牌
+func Logout(c *fiber.Ctx) error {
+	s, principal, err := currentPrincipal(c)
+	if err != nil {
+		return err
+	}
+	return s.RevokePrincipal(principal)
+}
+
+// LogoutAll 注销当前用户全部令牌
+func LogoutAll(c *fiber.Ctx) error {
+	s, principal, err := currentPrincipal(c)
+	if err != nil {
+		return err
+	}
+	return s.RevokeUser(principal.Id, time.Now())
+}
+
+func currentPrincipal(c *fiber.Ctx) (*Server, *UserPrincipal, error) {
+	s := serverOf(c)
+	if s == nil {
+		return nil, nil, errors.New("server not found")
+	}
+	principal, err := ResolveUser(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, principal, nil
+}
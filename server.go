@@ -1,17 +1,13 @@
 package web
 
 import (
-	"encoding/base64"
-	"github.com/elancom/go-util/bytes"
-	"github.com/elancom/go-util/crypto"
-	"github.com/elancom/go-util/json"
 	. "github.com/elancom/go-util/lang"
-	"github.com/elancom/go-util/sign"
-	"github.com/elancom/go-util/str"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/valyala/fasthttp"
 	"log"
 	"net/http"
+	"time"
 )
 
 func newDefaultConfig() Config {
@@ -22,6 +18,7 @@ func newDefaultConfig() Config {
 
 		// 地址过来
 		IgnoreUrls: make([]string, 0),
+		LoginUrls:  defaultLoginUrls(),
 
 		// 跨域配置
 		CorsEnable: false,
@@ -44,7 +41,6 @@ func (t *Text) Error() string {
 
 func NewServer(config ...Config) *Server {
 	s := new(Server)
-	s.ignoreUrls = make([]string, 0)
 
 	var conf Config
 	if len(config) > 0 {
@@ -55,16 +51,93 @@ func NewServer(config ...Config) *Server {
 	s.config = conf
 
 	// 权限忽略地址
-	s.setIgnoreUrls(s.config.IgnoreUrls)
+	if s.config.LoginUrls == nil {
+		s.config.LoginUrls = defaultLoginUrls()
+	}
+	s.ignore = NewPathMatcher(s.config.IgnoreUrls...)
+	s.login = NewPathMatcher(s.config.LoginUrls...)
+
+	// 签名nonce缓存
+	s.nonceStore = s.config.NonceStore
+	if s.nonceStore == nil {
+		s.nonceStore = NewMemoryNonceStore()
+	}
+
+	// 令牌编解码
+	s.codec = s.config.TokenCodec
+	if s.codec == nil && s.config.Jwt != nil {
+		s.codec = NewJwtCodec(*s.config.Jwt)
+	}
+	if s.codec == nil {
+		ring := s.config.TokenKeyring
+		if ring == nil {
+			key := s.config.TokenKey
+			if len(key) == 0 {
+				key = defaultTokenKey
+			}
+			ring = newSingleKeyring(key)
+		}
+		s.codec = newDefaultTokenCodec(ring, s.config.LegacyTokenUntil)
+	}
 
 	return s
 }
 
 type Config struct {
-	AuthEnable bool     // TK认证
+	AuthEnable bool     // TK认证(全局, 可由Server.Secure按路由覆盖)
 	SignEnable bool     // 签名认证(依赖TK认证)
 	EncEnable  bool     // 加密
-	IgnoreUrls []string // 忽略地址
+	IgnoreUrls []string // 忽略认证地址(规则见PathMatcher: /x 前缀, =/x 精确)
+	LoginUrls  []string // 登录地址: 忽略认证且不加密(nil:默认 /login/**)
+
+	// 令牌配置
+	TokenKey     []byte     // 令牌秘钥(16/24/32位)
+	TokenKeyring *Keyring   // 令牌秘钥环(优先于TokenKey, 支持轮换)
+	Jwt          *JwtConfig // JWT令牌(优先于TokenKeyring)
+	TokenCodec   TokenCodec // 令牌编解码(优先于Jwt, 默认AES-GCM)
+
+	// 旧版(AES-ECB, 无完整性校验)令牌迁移截止时间
+	// 零值(默认): 不接受旧版令牌; 迁移期间设为明确的截止时间, 到期自动停用
+	LegacyTokenUntil time.Time
+
+	// 加密版本(x-enc)
+	EncVersions []string // 接受的版本(默认 1,2)
+	EncVersion  string   // 请求未加密时的响应版本(默认EncVersions第一个)
+	EncAad      bool     // x-enc:2 使用 "METHOD /path" 作为附加数据
+
+	// 签名方式
+	SignMode    SignMode // 默认SignModeLegacy
+	SignHeaders []string // 规范请求签名包含的请求头
+
+	// 响应签名: x-sign 响应头, hex(HMAC-SHA256(secret, 响应体)), 见 VerifyResponse
+	RespSignEnable bool
+
+	// 签名防重放: 需要 x-timestamp(毫秒)/x-nonce 请求头并参与签名
+	SignReplayEnable bool
+	SignWindow       time.Duration // 时间窗口(0:默认5分钟)
+	NonceStore       NonceStore    // nonce缓存(nil:内存)
+
+	// 令牌吊销(nil:不检查)
+	RevocationStore RevocationStore
+
+	// 角色/权限解析(与令牌中的角色/权限合并)
+	PermissionResolver PermissionResolver
+
+	// 令牌有效期
+	TokenTTL     time.Duration // 有效期(0:不过期)
+	TokenSkew    time.Duration // 时钟偏差容忍(0:默认30秒)
+	TokenRefresh time.Duration // 剩余有效期小于该值时下发新令牌(0:不续期)
+
+	// 参数绑定错误明细(默认参数名/位置/来源)
+	BindErrDetail BindDetail
+
+	// 请求体大小(0:默认4MB), 文件上传需按需调大(流式上传时上传请求不受限制)
+	BodyLimit int
+	// 文件上传默认限制(见UploadOptions), Stream: 流式上传
+	Upload UploadOptions
+
+	// 按Accept协商的响应编码(JSON外, 需已注册, 如 application/xml), 空:始终JSON
+	Codecs []string
 
 	// 跨域配置
 	CorsEnable       bool // 是否开启跨域
@@ -79,22 +152,50 @@ type Config struct {
 type Server struct {
 	App        *fiber.App
 	config     Config
-	ignoreUrls []string // 如果很多再用map
+	ignore     *PathMatcher
+	login      *PathMatcher
+	policies   []policyRule // 路由安全策略
+	codec      TokenCodec
+	nonceStore NonceStore
 }
 
-func (s *Server) setIgnoreUrls(urls []string) {
-	if len(urls) == 0 {
-		return
-	}
-	ignoreUrls := make([]string, len(urls))
-	for _, url := range urls {
-		ignoreUrls = append(ignoreUrls, url)
-	}
-	s.ignoreUrls = ignoreUrls
+// 当前请求所属服务
+func serverOf(c *fiber.Ctx) *Server {
+	s, _ := c.Context().Value(ctxKeyServer).(*Server)
+	return s
+}
+
+// MakeToken 使用服务的令牌编解码生成令牌
+func (s *Server) MakeToken(id int64, username string, secret string) (string, error) {
+	return MakeTokenWith(s.codec, id, username, secret)
+}
+
+// EncodeToken 使用服务的令牌编解码生成令牌(可携带角色/权限)
+func (s *Server) EncodeToken(principal *UserPrincipal) (string, error) {
+	return s.codec.Encode(principal)
+}
+
+// GetUserPrincipal 使用服务的令牌编解码解析令牌
+func (s *Server) GetUserPrincipal(token string) (*UserPrincipal, error) {
+	return GetUserPrincipal(token, s.codec)
+}
+
+func defaultLoginUrls() []string {
+	return []string{"/login/**"}
 }
 
 func (s *Server) Init() *Server {
 	s.App = s.newFiber()
+	s.routeMatcher(s.ignore)
+	s.routeMatcher(s.login)
+
+	// 服务上下文
+	s.App.Use(func(c *fiber.Ctx) error {
+		c.Context().SetUserValue(ctxKeyServer, s)
+		err := c.Next()
+		removeTempUploads(c, false)
+		return err
+	})
 
 	if s.config.CorsEnable {
 		s.App.Use(cors.New(cors.Config{
@@ -107,15 +208,6 @@ func (s *Server) Init() *Server {
 		}))
 	}
 
-	// 加密字符串
-	encStr := func(principal *UserPrincipal, s string) (string, error) {
-		sb, encErr := crypto.AesEcbEncrypt([]byte(s), []byte(principal.Secret))
-		if encErr != nil {
-			return "", NewErr("enc err")
-		}
-		return base64.StdEncoding.EncodeToString(sb), nil
-	}
-
 	// 消息处理
 	s.App.Use(func(c *fiber.Ctx) error {
 		log.Println("处理")
@@ -126,15 +218,47 @@ func (s *Server) Init() *Server {
 			err = NewErr("处理器响应空消息")
 		}
 
-		if _, ok := err.(*Msg); ok {
-			js, _ := json.ToJson(err)
-			log.Println("[返回JSON消息]", js)
-			return c.JSON(err)
+		// 文件/流/重定向等
+		if r, ok := err.(Responder); ok {
+			log.Println("[返回响应]", r.Error())
+			header := new(fasthttp.ResponseHeader)
+			c.Response().Header.CopyTo(header)
+			if respErr := r.Respond(c); respErr != nil {
+				// 不外泄内部错误(文件路径等), 丢弃已写入的响应头(Content-Disposition等)
+				log.Println("[响应错误]", respErr)
+				header.CopyTo(&c.Response().Header)
+				c.Response().ResetBody()
+				c.Status(http.StatusOK)
+				err = NewErr("response err")
+			} else {
+				if _, ok = r.(payloader); ok {
+					s.signResponse(c)
+				}
+				return nil
+			}
+		}
+
+		if msg, ok := err.(*Msg); ok {
+			b, codec, encErr := encodeMsg(c, msg)
+			if encErr != nil {
+				return encErr
+			}
+			log.Println("[返回消息]", codec.ContentType(), string(b))
+			c.Response().Header.SetContentType(codec.ContentType())
+			if sendErr := c.Send(b); sendErr != nil {
+				return sendErr
+			}
+			s.signResponse(c)
+			return nil
 		}
 		if _, ok := err.(*Text); ok {
 			log.Println("[返回文本消息]", err.Error())
 			c.Response().Header.SetContentType(fiber.MIMETextPlain)
-			return c.SendString(err.Error())
+			if sendErr := c.SendString(err.Error()); sendErr != nil {
+				return sendErr
+			}
+			s.signResponse(c)
+			return nil
 		}
 
 		// 未知错误
@@ -152,55 +276,11 @@ func (s *Server) Init() *Server {
 			return err
 		}
 
-		if !s.config.EncEnable {
-			return err
-		}
-
-		if str.HasPrefix(c.Path(), "/login") {
+		if !s.policyOf(c).Enc {
 			return err
 		}
 
-		userPrincipal, ok := c.Context().Value("principal").(*UserPrincipal)
-		if !ok {
-			return NewErr("user not found")
-		}
-
-		var body any
-		switch err.(type) {
-		case *Text:
-			log.Println("[将要加密文本]", err.Error())
-			body = err.Error()
-		case *Msg:
-			js, _ := json.ToJson(err)
-			log.Println("[将要加密JSON]", js)
-			body = err
-		default:
-			// 未知错误
-			return err
-		}
-
-		// 加密 转字符串
-		encSs := ""
-		switch body.(type) {
-		case string:
-		default:
-			toJson, jsErr := json.ToJson(body)
-			if jsErr != nil {
-				return jsErr
-			}
-			encSs = toJson
-		}
-		encSs, encErr := encStr(userPrincipal, encSs)
-		if encErr != nil {
-			log.Println("[enc]加密错误")
-			return err
-		}
-		body = encSs
-
-		// 标记加密头
-		c.Response().Header.Set("x-enc", "1")
-
-		return NewText(body.(string))
+		return s.encryptResponse(c, err)
 	})
 
 	// 错误转换
@@ -214,29 +294,35 @@ func (s *Server) Init() *Server {
 			err = NewErr(err.Error())
 		} else if err == NotAuthorized { // 无权限
 			err = NewErr(err.Error())
+		} else if err == TokenExpired || err == TokenNotActive { // 令牌过期
+			err = NewMsg(CodeTokenExpired, err.Error())
+		} else if err == TokenRevoked { // 令牌吊销
+			err = NewMsg(CodeTokenRevoked, err.Error())
+		} else if be, ok := err.(*BindError); ok { // 参数绑定
+			err = s.bindErrMsg(be)
 		}
 		return err
 	})
 
-	// 认证
-	s.App.Use(func(c *fiber.Ctx) error {
-		if !s.config.AuthEnable {
-			return c.Next()
-		}
-
-		// 地址匹配
-		path := c.Path()
-		if len(path) > 0 {
-			if str.HasPrefix(path, "/login/") {
+	// 流式上传: 超出 BodyLimit 的请求体仅允许上传请求(无需签名)
+	if s.config.Upload.Stream {
+		s.App.Use(func(c *fiber.Ctx) error {
+			if !s.bodyTooLarge(c) {
 				return c.Next()
 			}
-			if len(s.ignoreUrls) > 0 {
-				for _, url := range s.ignoreUrls {
-					if str.HasPrefix(url, path) {
-						return c.Next()
-					}
-				}
+			// 请求体可能未读完, 不再复用连接
+			c.Context().SetConnectionClose()
+			if !isMultipart(c) || s.policyOf(c).Sign {
+				return NewErr("request entity too large")
 			}
+			return c.Next()
+		})
+	}
+
+	// 认证
+	s.App.Use(func(c *fiber.Ctx) error {
+		if !s.policyOf(c).Auth {
+			return c.Next()
 		}
 
 		principal, err := parseUserPrincipal(c)
@@ -247,52 +333,21 @@ func (s *Server) Init() *Server {
 		// 设置认证信息到上下文
 		c.Context().SetUserValue("principal", principal)
 
+		// 续期
+		s.refreshToken(c, principal, time.Now())
+
 		return c.Next()
 	})
 
 	// 签名验证
 	s.App.Use(func(c *fiber.Ctx) error {
-		if !s.config.AuthEnable || !s.config.SignEnable {
+		policy := s.policyOf(c)
+		if !policy.Sign {
 			return c.Next()
 		}
 
-		principal, ok := c.Context().Value("principal").(*UserPrincipal)
-		if !ok {
-			return c.Next()
-		}
-		if principal.Secret == "" {
-			return NewErr("use x-sign, but secret not found")
-		}
-
-		xSign := c.Get("x-sign")
-		if str.IsBlank(xSign) {
-			return NewErr("x-sign err")
-		}
-
-		// 取内容
-		ss := ""
-		switch c.Method() {
-		case http.MethodGet:
-			qs := c.Request().URI().QueryString()
-			if len(qs) == 0 {
-				return NewErr("qs err")
-			}
-			ss = string(qs)
-		case http.MethodPost:
-			body := c.Body()
-			if len(body) > 0 {
-				body = bytes.TrimUint8(body, 34) // 34:双引号
-			}
-			if len(body) == 0 {
-				return NewErr("body err")
-			}
-			ss = string(body)
-		}
-
-		log.Println("[sign]字符串", ss)
-		log.Println("[sign]签名", xSign)
-		if !sign.CheckStr(ss, principal.Secret, xSign) {
-			return NewErr("sign err")
+		if err := s.checkSign(c, policy); err != nil {
+			return err
 		}
 
 		return c.Next()
@@ -300,59 +355,17 @@ func (s *Server) Init() *Server {
 
 	// 解密
 	s.App.Use(func(c *fiber.Ctx) error {
-		if !s.config.EncEnable {
+		if !s.policyOf(c).Enc {
 			return c.Next()
 		}
 
-		xEnc := c.Get("x-enc")
-		if xEnc != "1" {
+		// 文件上传不加密请求
+		if !isEncRequest(c) || isMultipart(c) {
 			return c.Next()
 		}
 
-		// 从tk中取加密秘钥
-		principal, ok := c.Context().Value("principal").(*UserPrincipal)
-		if !ok {
-			return NewErr("use x-enc, but not found principal")
-		}
-		if principal.Secret == "" {
-			return NewErr("use x-enc, but secret not found")
-		}
-
-		// 解密
-		switch c.Method() {
-		case http.MethodGet: // ?*****
-			d3 := string(c.Request().URI().QueryString())
-			log.Println("密文:", d3)
-			if d3 != "" {
-				d3b, err := base64.StdEncoding.DecodeString(d3)
-				if err != nil {
-					return err
-				}
-				decrypt, err := crypto.AesEcbDecrypt(d3b, []byte(principal.Secret))
-				if err != nil {
-					return NewErr("dec err")
-				}
-				log.Println("解密:", string(decrypt))
-				c.Request().URI().SetQueryStringBytes(decrypt)
-			}
-		case http.MethodPost:
-			body := c.Body()
-			if len(body) > 0 {
-				body = bytes.TrimUint8(body, 34) // 34:双引号
-				log.Println("密文:", string(body))
-				bbs, err := base64.StdEncoding.DecodeString(string(body))
-				if err != nil {
-					return err
-				}
-				decrypt, err := crypto.AesEcbDecrypt(bbs, []byte(principal.Secret))
-				if err != nil {
-					return NewErr("dec err")
-				}
-				log.Println("解密:", string(decrypt))
-				// 修改内容及长度
-				c.Request().SetBody(decrypt)
-				c.Request().Header.Set("Content-Length", str.String(len(body)))
-			}
+		if err := s.decryptRequest(c); err != nil {
+			return err
 		}
 		return c.Next()
 	})
@@ -371,7 +384,13 @@ func (s *Server) newFiber() *fiber.App {
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			log.Println("[系统错误]", err)
 			return c.JSON(NewErr("InternalServerError"))
-		}}
+		},
+		BodyLimit: s.config.BodyLimit,
+
+		// 流式上传: 上传请求体由 readMultipartStream 读取
+		StreamRequestBody:            s.config.Upload.Stream,
+		DisablePreParseMultipartForm: s.config.Upload.Stream,
+	}
 	fa := fiber.New(config)
 	return fa
 }
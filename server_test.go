@@ -22,6 +22,8 @@ func TestName(t *testing.T) {
 		SignEnable: false,
 		AuthEnable: true,
 		EncEnable:  true,
+		// testToken 为旧版令牌
+		LegacyTokenUntil: time.Now().Add(time.Hour),
 	})
 	server.Init()
 
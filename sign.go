@@ -0,0 +1,271 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/elancom/go-util/bytes"
+	. "github.com/elancom/go-util/lang"
+	"github.com/elancom/go-util/sign"
+	"github.com/elancom/go-util/str"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignMode 签名方式
+type SignMode int
+
+const (
+	// SignModeLegacy 查询串(GET)/请求体(POST) + MD5(sign.Str)
+	SignModeLegacy SignMode = iota
+	// SignModeCanonical 规范请求(方法/路径/排序查询串/指定请求头/请求体摘要) + HMAC-SHA256
+	SignModeCanonical
+)
+
+// 默认签名时间窗口
+const defaultSignWindow = 5 * time.Minute
+
+// NonceStore 签名nonce缓存(防重放)
+type NonceStore interface {
+	// Use 记录nonce, 窗口内已使用过返回false
+	Use(nonce string, ttl time.Duration) (bool, error)
+}
+
+// NewMemoryNonceStore 内存nonce缓存(单机)
+func NewMemoryNonceStore() NonceStore {
+	m := new(memoryNonceStore)
+	m.nonces = newTtlMap[string, struct{}]()
+	return m
+}
+
+type memoryNonceStore struct {
+	nonces *ttlMap[string, struct{}]
+}
+
+func (m *memoryNonceStore) Use(nonce string, ttl time.Duration) (bool, error) {
+	return m.nonces.putIfAbsent(nonce, struct{}{}, ttl, time.Now()), nil
+}
+
+// 签名验证
+func (s *Server) checkSign(c *fiber.Ctx, policy *Policy) error {
+	// 签名秘钥: 用户秘钥优先, 否则使用策略秘钥
+	secret, scope := policy.SignKey, ""
+	if principal, ok := c.Context().Value("principal").(*UserPrincipal); ok {
+		secret, scope = principal.Secret, principal.Key
+	}
+	if secret == "" {
+		return NewErr("use x-sign, but secret not found")
+	}
+
+	xSign := c.Get("x-sign")
+	if str.IsBlank(xSign) {
+		return NewErr("x-sign missing")
+	}
+
+	if s.config.SignMode == SignModeCanonical {
+		return s.checkCanonicalSign(c, secret, scope, xSign)
+	}
+
+	// 取内容
+	ss := ""
+	if hasBody(c) {
+		body := c.Body()
+		if len(body) > 0 {
+			body = bytes.TrimUint8(body, 34) // 34:双引号
+		}
+		if len(body) == 0 {
+			return NewErr("body err")
+		}
+		ss = string(body)
+	} else {
+		qs := c.Request().URI().QueryString()
+		if len(qs) == 0 {
+			return NewErr("qs err")
+		}
+		ss = string(qs)
+	}
+
+	// 防重放: 时间戳和nonce参与签名
+	if s.config.SignReplayEnable {
+		ts, nonce, err := checkReplayHeaders(c, s.signWindow())
+		if err != nil {
+			return err
+		}
+		ss = ReplaySignStr(ss, ts, nonce)
+	}
+
+	log.Println("[sign]字符串", ss)
+	log.Println("[sign]签名", xSign)
+	if !sign.CheckStr(ss, secret, xSign) {
+		return NewErr("sign err")
+	}
+
+	// 签名通过后记录nonce
+	if s.config.SignReplayEnable {
+		return s.useNonce(scope, c.Get("x-nonce"))
+	}
+	return nil
+}
+
+// 规范请求签名验证
+func (s *Server) checkCanonicalSign(c *fiber.Ctx, secret string, scope string, xSign string) error {
+	names := s.config.SignHeaders
+	nonce := ""
+	if s.config.SignReplayEnable {
+		_, n, err := checkReplayHeaders(c, s.signWindow())
+		if err != nil {
+			return err
+		}
+		nonce = n
+		names = append([]string{"x-timestamp", "x-nonce"}, names...)
+	}
+
+	headers := make(map[string]string, len(names))
+	for _, name := range names {
+		headers[strings.ToLower(name)] = c.Get(name)
+	}
+	canonical := CanonicalRequest(c.Method(), c.Path(), string(c.Request().URI().QueryString()), headers, c.Body())
+
+	log.Println("[sign]规范请求", canonical)
+	log.Println("[sign]签名", xSign)
+	if !hmac.Equal([]byte(SignCanonical(canonical, secret)), []byte(str.ToLower(xSign))) {
+		return NewErr("sign err")
+	}
+
+	if s.config.SignReplayEnable {
+		return s.useNonce(scope, nonce)
+	}
+	return nil
+}
+
+// CanonicalRequest 规范请求字符串
+//
+//	METHOD
+//	/path (原始请求路径, 不解码, 如 /file/%E6%8A%A5%E8%A1%A8)
+//	排序后的查询串(按原始 k=v 排序, 不解码)
+//	name:value 每个签名请求头一行(小写名称排序)
+//	签名请求头名称(;分隔)
+//	hex(sha256(body))
+func CanonicalRequest(method string, path string, rawQuery string, headers map[string]string, body []byte) string {
+	b := strings.Builder{}
+	b.WriteString(strings.ToUpper(method))
+	b.WriteByte('\n')
+	b.WriteString(path)
+	b.WriteByte('\n')
+
+	// 查询串
+	if rawQuery != "" {
+		pairs := strings.Split(rawQuery, "&")
+		sort.Strings(pairs)
+		b.WriteString(strings.Join(pairs, "&"))
+	}
+	b.WriteByte('\n')
+
+	// 请求头
+	names := make([]string, 0, len(headers))
+	values := make(map[string]string, len(headers))
+	for name, value := range headers {
+		name = strings.ToLower(strings.TrimSpace(name))
+		names = append(names, name)
+		values[name] = strings.TrimSpace(value)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(values[name])
+		b.WriteByte('\n')
+	}
+	b.WriteString(strings.Join(names, ";"))
+	b.WriteByte('\n')
+
+	// 请求体摘要
+	sum := sha256.Sum256(body)
+	b.WriteString(hex.EncodeToString(sum[:]))
+	return b.String()
+}
+
+// SignCanonical 规范请求签名 hex(HMAC-SHA256(secret, canonical))
+func SignCanonical(canonical string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReplaySignStr 防重放签名字符串: 原内容追加时间戳(毫秒)和nonce
+func ReplaySignStr(ss string, timestamp string, nonce string) string {
+	return ss + "&x-timestamp=" + timestamp + "&x-nonce=" + nonce
+}
+
+// 时间戳/nonce检查
+func checkReplayHeaders(c *fiber.Ctx, window time.Duration) (string, string, error) {
+	ts, nonce := c.Get("x-timestamp"), c.Get("x-nonce")
+	if ts == "" || nonce == "" {
+		return "", "", NewErr("x-timestamp/x-nonce missing")
+	}
+	if len(nonce) < 8 || len(nonce) > 64 {
+		return "", "", NewErr("x-nonce err")
+	}
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", "", NewErr("x-timestamp err")
+	}
+	diff := time.Since(time.UnixMilli(ms))
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > window {
+		return "", "", NewErr("x-timestamp expired")
+	}
+	return ts, nonce, nil
+}
+
+// 记录nonce, 重复使用拒绝
+func (s *Server) useNonce(scope string, nonce string) error {
+	fresh, err := s.nonceStore.Use(scope+":"+nonce, 2*s.signWindow())
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return NewErr("x-nonce reused")
+	}
+	return nil
+}
+
+func (s *Server) signWindow() time.Duration {
+	if s.config.SignWindow > 0 {
+		return s.config.SignWindow
+	}
+	return defaultSignWindow
+}
+
+// 响应签名(用户秘钥优先, 否则使用策略秘钥)
+func (s *Server) signResponse(c *fiber.Ctx) {
+	if !s.config.RespSignEnable {
+		return
+	}
+	secret := s.policyOf(c).SignKey
+	if principal, ok := c.Context().Value("principal").(*UserPrincipal); ok {
+		secret = principal.Secret
+	}
+	if secret == "" {
+		return
+	}
+	c.Set("x-sign", SignResponse(c.Response().Body(), secret))
+}
+
+// SignResponse 响应签名 hex(HMAC-SHA256(secret, body))
+func SignResponse(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyResponse 客户端验证响应签名(x-sign 响应头)
+func VerifyResponse(body []byte, secret string, sign string) bool {
+	return sign != "" && hmac.Equal([]byte(SignResponse(body, secret)), []byte(str.ToLower(sign)))
+}
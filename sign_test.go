@@ -0,0 +1,106 @@
+package web
+
+import (
+	"github.com/elancom/go-util/lang"
+	"github.com/elancom/go-util/sign"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignReplay(t *testing.T) {
+	server := NewServer(Config{SignReplayEnable: true, SignWindow: time.Minute})
+	server.Init()
+	server.Secure(server.App, Policy{Sign: true, SignKey: "hook-key"})
+	server.App.Get("/notify", Use(func() error { return lang.NewOk() }))
+
+	request := func(ts time.Time, nonce string, signed string) *http.Request {
+		request, _ := http.NewRequest(http.MethodGet, "/notify?a=1", nil)
+		ms := strconv.FormatInt(ts.UnixMilli(), 10)
+		request.Header.Set("x-timestamp", ms)
+		request.Header.Set("x-nonce", nonce)
+		request.Header.Set("x-sign", sign.Str(ReplaySignStr(signed, ms, nonce), "hook-key"))
+		return request
+	}
+
+	now := time.Now()
+	if msg := testMsg(t, server, request(now, "nonce-0001", "a=1")); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+	// 重放
+	if msg := testMsg(t, server, request(now, "nonce-0001", "a=1")); msg.IsOk() {
+		t.Fatal(msg)
+	}
+	// 超出时间窗口
+	if msg := testMsg(t, server, request(now.Add(-2*time.Minute), "nonce-0002", "a=1")); msg.IsOk() {
+		t.Fatal(msg)
+	}
+	// 签名错误不占用nonce
+	if msg := testMsg(t, server, request(now, "nonce-0003", "a=2")); msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request(now, "nonce-0003", "a=1")); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+}
+
+func TestSignCanonical(t *testing.T) {
+	server := NewServer(Config{SignMode: SignModeCanonical, SignHeaders: []string{"x-tenant"}})
+	server.Init()
+	server.Secure(server.App, Policy{Sign: true, SignKey: "hook-key"})
+	ok := Use(func() error { return lang.NewOk() })
+	server.App.Get("/items", ok)
+	server.App.Get("/other", ok)
+
+	headers := map[string]string{"x-tenant": "t1"}
+	signed := SignCanonical(CanonicalRequest(http.MethodGet, "/items", "", headers, nil), "hook-key")
+
+	request := func(path string, tenant string) *http.Request {
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set("x-tenant", tenant)
+		request.Header.Set("x-sign", signed)
+		return request
+	}
+
+	// 无查询串的GET
+	if msg := testMsg(t, server, request("/items", "t1")); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+	// 路径/请求头不一致
+	if msg := testMsg(t, server, request("/other", "t1")); msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if msg := testMsg(t, server, request("/items", "t2")); msg.IsOk() {
+		t.Fatal(msg)
+	}
+
+	// 查询串顺序无关
+	c1 := CanonicalRequest(http.MethodGet, "/items", "b=2&a=1", nil, nil)
+	c2 := CanonicalRequest(http.MethodGet, "/items", "a=1&b=2", nil, nil)
+	if c1 != c2 {
+		t.Fatal(c1, c2)
+	}
+}
+
+func TestRespSign(t *testing.T) {
+	server := NewServer(Config{AuthEnable: true, EncEnable: true, RespSignEnable: true})
+	server.Init()
+	server.App.Get("/get", Use(func() error { return lang.NewOk("data") }))
+
+	token, _ := server.MakeToken(1, "tom", testSecret)
+	request, _ := http.NewRequest(http.MethodGet, "/get", nil)
+	request.Header.Set("x-token", token)
+	resp, err := server.App.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !VerifyResponse(body, testSecret, resp.Header.Get("x-sign")) {
+		t.Fatal("response sign err")
+	}
+	if VerifyResponse(append(body, ' '), testSecret, resp.Header.Get("x-sign")) {
+		t.Fatal("tampered response accepted")
+	}
+}
@@ -0,0 +1,248 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/elancom/go-util/crypto"
+	"github.com/elancom/go-util/rand"
+	"github.com/elancom/go-util/str"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"time"
+)
+
+// CodeTokenExpired 令牌过期/未生效消息码
+const CodeTokenExpired = 401
+
+// TokenExpired 令牌过期
+var TokenExpired = errors.New("TokenExpired")
+
+// TokenNotActive 令牌未生效(签发时间晚于当前时间)
+var TokenNotActive = errors.New("TokenNotActive")
+
+// 默认时钟偏差容忍
+const defaultTokenSkew = 30 * time.Second
+
+// 默认令牌秘钥(仅用于兼容旧版本, 生产环境请配置 Config.TokenKey)
+var defaultTokenKey = []byte("1234567890123456")
+
+// TokenCodec 令牌编解码
+type TokenCodec interface {
+	Encode(principal *UserPrincipal) (string, error)
+	Decode(token string) (*UserPrincipal, error)
+}
+
+// NewAesTokenCodec AES-ECB+base64令牌
+func NewAesTokenCodec(key []byte) TokenCodec {
+	c := new(aesTokenCodec)
+	c.key = key
+	return c
+}
+
+type aesTokenCodec struct {
+	key []byte
+}
+
+func (a *aesTokenCodec) Encode(principal *UserPrincipal) (string, error) {
+	marshal, err := json.Marshal(principal)
+	if err != nil {
+		return "", err
+	}
+
+	encrypt, err := crypto.AesEcbEncrypt(marshal, a.key)
+	if err != nil {
+		return "", errors.New("encrypt(0)")
+	}
+
+	return base64.StdEncoding.EncodeToString(encrypt), nil
+}
+
+func (a *aesTokenCodec) Decode(token string) (*UserPrincipal, error) {
+	if str.IsBlank(token) {
+		return nil, errors.New("token err")
+	}
+
+	tokenBytes, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("token err(DC)")
+	}
+
+	decrypt, err := crypto.AesEcbDecrypt(tokenBytes, a.key)
+	if err != nil {
+		return nil, errors.New("token err(0)")
+	}
+
+	principal := new(UserPrincipal)
+	err = json.Unmarshal(decrypt, principal)
+	if err != nil {
+		return nil, errors.New("token err(1)")
+	}
+
+	if err = checkPrincipal(principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// 令牌版本前缀
+const gcmTokenPrefix = "2."
+
+// NewGcmTokenCodec AES-GCM令牌
+func NewGcmTokenCodec(key []byte) TokenCodec {
+	return NewKeyringTokenCodec(newSingleKeyring(key))
+}
+
+// NewKeyringTokenCodec AES-GCM令牌(格式: 2.kid.base64url(nonce+密文)), 支持秘钥轮换
+func NewKeyringTokenCodec(ring *Keyring) TokenCodec {
+	c := new(gcmTokenCodec)
+	c.ring = ring
+	return c
+}
+
+type gcmTokenCodec struct {
+	ring *Keyring
+}
+
+func (g *gcmTokenCodec) Encode(principal *UserPrincipal) (string, error) {
+	marshal, err := json.Marshal(principal)
+	if err != nil {
+		return "", err
+	}
+
+	encrypt, err := g.ring.Encrypt(marshal, []byte(gcmTokenPrefix))
+	if err != nil {
+		return "", errors.New("encrypt(0)")
+	}
+
+	return gcmTokenPrefix + encrypt, nil
+}
+
+func (g *gcmTokenCodec) Decode(token string) (*UserPrincipal, error) {
+	if !str.HasPrefix(token, gcmTokenPrefix) {
+		return nil, errors.New("token err(V)")
+	}
+
+	decrypt, err := g.ring.Decrypt(token[len(gcmTokenPrefix):], []byte(gcmTokenPrefix))
+	if err != nil {
+		return nil, errors.New("token err(0)")
+	}
+
+	principal := new(UserPrincipal)
+	if err = json.Unmarshal(decrypt, principal); err != nil {
+		return nil, errors.New("token err(1)")
+	}
+
+	if err = checkPrincipal(principal); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// 旧版令牌(秘钥环中任一秘钥)
+type keyringAesTokenCodec struct {
+	ring *Keyring
+}
+
+func (k *keyringAesTokenCodec) Encode(principal *UserPrincipal) (string, error) {
+	_, key := k.ring.Active()
+	return NewAesTokenCodec(key).Encode(principal)
+}
+
+func (k *keyringAesTokenCodec) Decode(token string) (*UserPrincipal, error) {
+	err := errors.New("token err(0)")
+	for _, id := range k.ring.Ids() {
+		key, _ := k.ring.Get(id)
+		principal, e := NewAesTokenCodec(key).Decode(token)
+		if e == nil {
+			return principal, nil
+		}
+		err = e
+	}
+	return nil, err
+}
+
+// NewMigrateTokenCodec 令牌迁移: 使用codec编码, 截止时间前仍接受legacy解码(零值:不接受)
+func NewMigrateTokenCodec(codec TokenCodec, legacy TokenCodec, until time.Time) TokenCodec {
+	m := new(migrateTokenCodec)
+	m.codec = codec
+	m.legacy = legacy
+	m.until = until
+	return m
+}
+
+type migrateTokenCodec struct {
+	codec  TokenCodec
+	legacy TokenCodec
+	until  time.Time
+}
+
+func (m *migrateTokenCodec) Encode(principal *UserPrincipal) (string, error) {
+	return m.codec.Encode(principal)
+}
+
+func (m *migrateTokenCodec) Decode(token string) (*UserPrincipal, error) {
+	principal, err := m.codec.Decode(token)
+	if err == nil {
+		return principal, nil
+	}
+	if m.until.IsZero() || time.Now().After(m.until) {
+		return nil, err
+	}
+	if str.HasPrefix(token, gcmTokenPrefix) {
+		return nil, err
+	}
+	return m.legacy.Decode(token)
+}
+
+// 默认令牌编解码, 仅在 legacyUntil 前接受旧版令牌
+func newDefaultTokenCodec(ring *Keyring, legacyUntil time.Time) TokenCodec {
+	if legacyUntil.IsZero() {
+		return NewKeyringTokenCodec(ring)
+	}
+	return NewMigrateTokenCodec(NewKeyringTokenCodec(ring), &keyringAesTokenCodec{ring: ring}, legacyUntil)
+}
+
+// 必要字段检查
+func checkPrincipal(principal *UserPrincipal) error {
+	if principal.Id == 0 || str.IsBlank(principal.Username) || str.IsBlank(principal.Key) || principal.Timestamp == 0 {
+		return errors.New("token err(2)")
+	}
+	return nil
+}
+
+// 有效期检查
+func (s *Server) checkTokenTime(principal *UserPrincipal, now time.Time) error {
+	issued := time.UnixMilli(principal.Timestamp)
+	skew := s.config.TokenSkew
+	if skew == 0 {
+		skew = defaultTokenSkew
+	}
+	if issued.After(now.Add(skew)) {
+		return TokenNotActive
+	}
+	if s.config.TokenTTL > 0 && now.After(issued.Add(s.config.TokenTTL+skew)) {
+		return TokenExpired
+	}
+	return nil
+}
+
+// 滑动续期: 剩余有效期不足时通过x-token-refresh下发新令牌
+func (s *Server) refreshToken(c *fiber.Ctx, principal *UserPrincipal, now time.Time) {
+	if s.config.TokenTTL <= 0 || s.config.TokenRefresh <= 0 {
+		return
+	}
+	expire := time.UnixMilli(principal.Timestamp).Add(s.config.TokenTTL)
+	if expire.Sub(now) > s.config.TokenRefresh {
+		return
+	}
+	fresh := *principal
+	fresh.Random = rand.RandomStr(32)
+	fresh.Timestamp = now.UnixMilli()
+	token, err := s.codec.Encode(&fresh)
+	if err != nil {
+		log.Println("[token]续期失败", err)
+		return
+	}
+	c.Set("x-token-refresh", token)
+}
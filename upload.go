@@ -0,0 +1,368 @@
+package web
+
+import (
+	"github.com/elancom/go-util/lang"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// 文件上传(multipart/form-data)
+//
+//	app.Post("/avatar", web.UseUserFile(func(user *web.UserPrincipal, file *web.UploadFile) error {
+//		...
+//	}, "file", web.UploadOptions{MaxFileSize: 2 << 20, AllowTypes: []string{"image/*"}}))
+//
+// 上传请求不参与 x-enc 请求解密(文件明文传输), 响应加密不受影响
+//
+// 默认请求体由fasthttp完整读取后解析, 大小受 Config.BodyLimit 限制, MaxFileSize/MaxTotalSize 在读取后检查
+// Config.Upload.Stream 开启流式上传:
+//   - 上传请求体不受 BodyLimit 限制, 文件边读边写入临时目录, 读取时按 Config.Upload 检查 MaxFileSize/MaxTotalSize
+//   - 非上传请求及需签名的上传请求(签名需完整请求体)超出 BodyLimit 时拒绝
+//   - 未被解析器取用的临时文件在请求结束时删除
+//   - 上传请求不支持 ResolveBody 整体解码, 表单值使用 ResolveParam 或 param 标签
+
+// UploadFile 上传文件
+type UploadFile struct {
+	*multipart.FileHeader
+	ContentType string // 按内容嗅探的MIME类型
+	Path        string // 临时文件路径(流式上传或 UploadOptions.Stream), 由调用方移动或删除(参数绑定失败时自动删除)
+	claimed     bool   // 已由解析器取用
+}
+
+// Open 打开文件, 已保存到临时目录时打开临时文件
+func (f *UploadFile) Open() (multipart.File, error) {
+	if f.Path != "" {
+		return os.Open(f.Path)
+	}
+	return f.FileHeader.Open()
+}
+
+// UploadOptions 上传限制
+type UploadOptions struct {
+	MaxFileSize  int64    // 单文件大小(0:不限制)
+	MaxTotalSize int64    // 请求内文件总大小(0:不限制)
+	AllowTypes   []string // 允许的MIME类型(按内容嗅探), 支持 image/* (空:不限制)
+	Stream       bool     // Config.Upload: 流式上传; 解析器参数: 复制到临时目录(Path)
+	TempDir      string   // 临时目录(默认 os.TempDir())
+}
+
+// 上传限制: 解析器参数 > Config.Upload
+func uploadOptionsOf(c *fiber.Ctx, opts []UploadOptions) UploadOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	if s := serverOf(c); s != nil {
+		return s.config.Upload
+	}
+	return UploadOptions{}
+}
+
+// ResolveFile 上传文件(同名多个时取第一个)
+func ResolveFile(name string, opts ...UploadOptions) Resolver[*UploadFile] {
+	files := ResolveFiles(name, opts...)
+	return func(c *fiber.Ctx) (*UploadFile, error) {
+		list, err := files(c)
+		if err != nil {
+			return nil, err
+		}
+		return list[0], nil
+	}
+}
+
+// ResolveFiles 上传文件(同名多个)
+func ResolveFiles(name string, opts ...UploadOptions) Resolver[[]*UploadFile] {
+	return func(c *fiber.Ctx) ([]*UploadFile, error) {
+		params, err := ResolveRequestParams(c)
+		if err != nil {
+			return nil, toBindErr(err, 0).withName(name)
+		}
+		opt := uploadOptionsOf(c, opts)
+		if params.uploads != nil {
+			return claimUploads(params.uploads, name, opt)
+		}
+		if params.multipart == nil || len(params.multipart.File[name]) == 0 {
+			return nil, newBindErr(name, sourceFile, ParamMissing)
+		}
+
+		if opt.MaxTotalSize > 0 {
+			total := int64(0)
+			for _, headers := range params.multipart.File {
+				for _, it := range headers {
+					total += it.Size
+				}
+			}
+			if total > opt.MaxTotalSize {
+				return nil, newBindErr(name, sourceFile, lang.NewErr("files too large"))
+			}
+		}
+
+		headers := params.multipart.File[name]
+		files := make([]*UploadFile, 0, len(headers))
+		for _, header := range headers {
+			file, err := openUpload(header, opt)
+			if err != nil {
+				removeUploads(files)
+				return nil, newBindErr(name, sourceFile, err)
+			}
+			file.claimed = true
+			files = append(files, file)
+		}
+		addTempUploads(c, files)
+		return files, nil
+	}
+}
+
+// 流式上传文件(已保存到临时目录), 按解析器参数检查
+func claimUploads(uploads map[string][]*UploadFile, name string, opt UploadOptions) ([]*UploadFile, error) {
+	files := uploads[name]
+	if len(files) == 0 {
+		return nil, newBindErr(name, sourceFile, ParamMissing)
+	}
+	if opt.MaxTotalSize > 0 {
+		total := int64(0)
+		for _, list := range uploads {
+			for _, it := range list {
+				total += it.Size
+			}
+		}
+		if total > opt.MaxTotalSize {
+			return nil, newBindErr(name, sourceFile, lang.NewErr("files too large"))
+		}
+	}
+	for _, it := range files {
+		if opt.MaxFileSize > 0 && it.Size > opt.MaxFileSize {
+			return nil, newBindErr(name, sourceFile, lang.NewErr("file too large"))
+		}
+		if !allowType(it.ContentType, opt.AllowTypes) {
+			return nil, newBindErr(name, sourceFile, lang.NewErr("file type not allowed"))
+		}
+	}
+	for _, it := range files {
+		it.claimed = true
+	}
+	return files, nil
+}
+
+// 检查大小/类型, 按需保存到临时目录
+func openUpload(header *multipart.FileHeader, opt UploadOptions) (*UploadFile, error) {
+	if opt.MaxFileSize > 0 && header.Size > opt.MaxFileSize {
+		return nil, lang.NewErr("file too large")
+	}
+	src, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	head, err := readHead(src)
+	if err != nil {
+		return nil, err
+	}
+	file := &UploadFile{FileHeader: header, ContentType: http.DetectContentType(head)}
+	if !allowType(file.ContentType, opt.AllowTypes) {
+		return nil, lang.NewErr("file type not allowed")
+	}
+
+	if !opt.Stream {
+		return file, nil
+	}
+	if file.Path, _, err = saveTemp(opt.TempDir, head, src); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// 读取前512字节(类型嗅探)
+func readHead(src io.Reader) ([]byte, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// 保存到临时目录, 返回路径及大小
+func saveTemp(dir string, head []byte, src io.Reader) (string, int64, error) {
+	dst, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer dst.Close()
+	size := int64(len(head))
+	if _, err = dst.Write(head); err == nil {
+		var n int64
+		n, err = io.Copy(dst, src)
+		size += n
+	}
+	if err != nil {
+		_ = os.Remove(dst.Name())
+		return "", 0, err
+	}
+	return dst.Name(), size, nil
+}
+
+// 流式读取上传表单: 文件边读边写入临时目录, 读取时按 Config.Upload 检查大小, 表单值合计不超过 BodyLimit
+func readMultipartStream(c *fiber.Ctx) (map[string][]string, map[string][]*UploadFile, error) {
+	opt := uploadOptionsOf(c, nil)
+	valueLimit := int64(c.App().Config().BodyLimit)
+	reader := multipart.NewReader(c.Context().RequestBodyStream(), string(c.Request().Header.MultipartFormBoundary()))
+
+	values := make(map[string][]string)
+	uploads := make(map[string][]*UploadFile)
+	total := int64(0)
+	fail := func(err error) (map[string][]string, map[string][]*UploadFile, error) {
+		for _, files := range uploads {
+			removeUploads(files)
+		}
+		return nil, nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(newBindErr("", sourceBody, err))
+		}
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+
+		// 表单值
+		if part.FileName() == "" {
+			b, err := io.ReadAll(io.LimitReader(part, valueLimit+1))
+			if err != nil {
+				return fail(newBindErr(name, sourceBody, err))
+			}
+			if valueLimit -= int64(len(b)); valueLimit < 0 {
+				return fail(newBindErr(name, sourceBody, lang.NewErr("form too large")))
+			}
+			values[name] = append(values[name], string(b))
+			continue
+		}
+
+		// 文件: 最多读取至超出限制1字节
+		var src io.Reader = part
+		limit, msg := int64(-1), ""
+		if opt.MaxFileSize > 0 {
+			limit, msg = opt.MaxFileSize, "file too large"
+		}
+		if opt.MaxTotalSize > 0 && (limit < 0 || opt.MaxTotalSize-total < limit) {
+			limit, msg = opt.MaxTotalSize-total, "files too large"
+		}
+		if limit >= 0 {
+			src = io.LimitReader(part, limit+1)
+		}
+		head, err := readHead(src)
+		if err != nil {
+			return fail(newBindErr(name, sourceFile, err))
+		}
+		path, size, err := saveTemp(opt.TempDir, head, src)
+		if err != nil {
+			return fail(newBindErr(name, sourceFile, err))
+		}
+		file := &UploadFile{
+			FileHeader:  &multipart.FileHeader{Filename: part.FileName(), Header: part.Header, Size: size},
+			ContentType: http.DetectContentType(head),
+			Path:        path,
+		}
+		uploads[name] = append(uploads[name], file)
+		if limit >= 0 && size > limit {
+			return fail(newBindErr(name, sourceFile, lang.NewErr(msg)))
+		}
+		total += size
+	}
+	for _, files := range uploads {
+		addTempUploads(c, files)
+	}
+	return values, uploads, nil
+}
+
+// 请求体是否超出 BodyLimit(分块传输视为超出)
+func (s *Server) bodyTooLarge(c *fiber.Ctx) bool {
+	n := c.Request().Header.ContentLength()
+	return n == -1 || n > s.App.Config().BodyLimit
+}
+
+// 删除已保存的临时文件
+func removeUploads(files []*UploadFile) {
+	for _, it := range files {
+		if it.Path != "" {
+			_ = os.Remove(it.Path)
+		}
+	}
+}
+
+// 请求内已保存的临时文件
+type tempUploads struct {
+	files []*UploadFile
+}
+
+func addTempUploads(c *fiber.Ctx, files []*UploadFile) {
+	if len(files) == 0 || files[0].Path == "" {
+		return
+	}
+	uploads, _ := ctxCached(c, ctxKeyUploads, func() (*tempUploads, error) { return new(tempUploads), nil })
+	uploads.files = append(uploads.files, files...)
+}
+
+// 删除请求内已保存的临时文件
+// all: 参数绑定失败时全部删除(处理器未接管); 否则仅删除未被解析器取用的(请求结束时)
+func removeTempUploads(c *fiber.Ctx, all bool) {
+	e, ok := c.Context().UserValue(ctxKeyUploads).(*ctxEntry[*tempUploads])
+	if !ok {
+		return
+	}
+	kept := e.value.files[:0]
+	for _, it := range e.value.files {
+		if all || !it.claimed {
+			removeUploads([]*UploadFile{it})
+		} else {
+			kept = append(kept, it)
+		}
+	}
+	e.value.files = kept
+}
+
+func allowType(contentType string, allows []string) bool {
+	if len(allows) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	for _, it := range allows {
+		if it == mediaType || strings.HasSuffix(it, "/*") && strings.HasPrefix(mediaType, it[:len(it)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否上传请求
+func isMultipart(c *fiber.Ctx) bool {
+	return bodyKindOf(c, nil) == bodyMultipart
+}
+
+// UseFile 注入上传文件
+func UseFile(handle HandleP1[*UploadFile], name string, opts ...UploadOptions) fiber.Handler {
+	return Bind1(handle, ResolveFile(name, opts...))
+}
+
+// UseFiles 注入上传文件(同名多个)
+func UseFiles(handle HandleP1[[]*UploadFile], name string, opts ...UploadOptions) fiber.Handler {
+	return Bind1(handle, ResolveFiles(name, opts...))
+}
+
+// UseUserFile 注入用户及上传文件
+func UseUserFile(handle HandleP2[*UserPrincipal, *UploadFile], name string, opts ...UploadOptions) fiber.Handler {
+	return Bind2(handle, ResolveUser, ResolveFile(name, opts...))
+}
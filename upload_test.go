@@ -0,0 +1,156 @@
+package web
+
+import (
+	"bytes"
+	"github.com/elancom/go-util/lang"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testPng = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+func newUploadRequest(t *testing.T, files map[string][]byte) *http.Request {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("name", "tom")
+	for name, content := range files {
+		part, err := writer.CreateFormFile(strings.Split(name, "#")[0], name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = part.Write(content)
+	}
+	_ = writer.Close()
+	request, _ := http.NewRequest(http.MethodPost, "/upload", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestUpload(t *testing.T) {
+	server := NewServer(Config{Upload: UploadOptions{MaxFileSize: 1024, AllowTypes: []string{"image/*"}}})
+	server.Init()
+	server.App.Post("/upload", Bind2(func(file *UploadFile, name string) error {
+		return lang.NewOk(name + ":" + file.ContentType)
+	}, ResolveFile("file"), ResolveParam("name")))
+
+	if msg := testMsg(t, server, newUploadRequest(t, map[string][]byte{"file": testPng})); msg.Data != "tom:image/png" {
+		t.Fatal(msg)
+	}
+	for _, files := range []map[string][]byte{
+		{"other": testPng},                  // 缺失
+		{"file": []byte("hello")},           // 类型
+		{"file": bytes.Repeat(testPng, 20)}, // 大小
+	} {
+		msg := testMsg(t, server, newUploadRequest(t, files))
+		if data, _ := msg.Data.(map[string]any); msg.Code != CodeBind || data["source"] != "file" {
+			t.Fatal(msg)
+		}
+	}
+}
+
+func TestUploadStream(t *testing.T) {
+	server := NewServer(Config{AuthEnable: true, EncEnable: true})
+	server.Init()
+	dir := t.TempDir()
+	opt := UploadOptions{MaxTotalSize: 1024, Stream: true, TempDir: dir}
+	server.App.Post("/upload", UseFiles(func(files []*UploadFile) error {
+		s := make([]string, 0, len(files))
+		for _, it := range files {
+			b, err := os.ReadFile(it.Path)
+			if err != nil {
+				return err
+			}
+			s = append(s, string(b))
+		}
+		return lang.NewOk(strings.Join(s, ","))
+	}, "file", opt))
+
+	// 上传请求不解密, 响应加密
+	token, _ := server.MakeToken(1, "tom", testSecret)
+	request := newUploadRequest(t, map[string][]byte{"file#1": []byte("a"), "file#2": []byte("b")})
+	request.Header.Set("x-token", token)
+	request.Header.Set("x-enc", EncV2)
+	resp, err := server.App.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := new(bytes.Buffer)
+	_, _ = body.ReadFrom(resp.Body)
+	plain, err := DecryptPayload(resp.Header.Get("x-enc"), body.String(), testSecret, nil)
+	if err != nil || (string(plain) != `{"code":200,"data":"a,b"}` && string(plain) != `{"code":200,"data":"b,a"}`) {
+		t.Fatal(string(plain), err)
+	}
+
+	request = newUploadRequest(t, map[string][]byte{"file": bytes.Repeat([]byte("a"), 2048)})
+	request.Header.Set("x-token", token)
+	if resp, err = server.App.Test(request); err != nil || resp.Header.Get("x-enc") == "" {
+		t.Fatal(resp, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatal(entries)
+	}
+	// 后续参数绑定失败: 删除已保存的临时文件
+	server.App.Post("/upload-age", Bind2(func(file *UploadFile, age int) error {
+		return lang.NewOk()
+	}, ResolveFile("file", opt), ResolveInt[int]("age")))
+	request = newUploadRequest(t, map[string][]byte{"file": []byte("a")})
+	request.URL.Path = "/upload-age"
+	request.Header.Set("x-token", token)
+	if _, err = server.App.Test(request); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatal(entries)
+	}
+}
+
+func TestUploadStreamBody(t *testing.T) {
+	dir := t.TempDir()
+	server := NewServer(Config{BodyLimit: 1024, Upload: UploadOptions{MaxFileSize: 8192, Stream: true, TempDir: dir}})
+	server.Init()
+	server.App.Post("/upload", Bind2(func(file *UploadFile, name string) error {
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		b, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		return lang.NewOk(name + ":" + strconv.Itoa(len(b)) + ":" + strconv.FormatBool(filepath.Dir(file.Path) == dir))
+	}, ResolveFile("file"), ResolveParam("name")))
+
+	// 超出 BodyLimit 的上传请求
+	if msg := testMsg(t, server, newUploadRequest(t, map[string][]byte{"file": bytes.Repeat([]byte("a"), 4096)})); msg.Data != "tom:4096:true" {
+		t.Fatal(msg)
+	}
+	// 未取用的文件请求结束时删除
+	if msg := testMsg(t, server, newUploadRequest(t, map[string][]byte{"file": []byte("a"), "other": []byte("b")})); !msg.IsOk() {
+		t.Fatal(msg)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatal(entries)
+	}
+	// 读取时检查大小
+	msg := testMsg(t, server, newUploadRequest(t, map[string][]byte{"file": bytes.Repeat([]byte("a"), 10000)}))
+	if data, _ := msg.Data.(map[string]any); msg.Code != CodeBind || data["source"] != "file" {
+		t.Fatal(msg)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatal(entries)
+	}
+
+	// 非上传请求仍受 BodyLimit 限制
+	request, _ := http.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{"name":"`+strings.Repeat("a", 2048)+`"}`))
+	request.Header.Set("Content-Type", "application/json")
+	if msg = testMsg(t, server, request); msg.IsOk() || msg.Msg != "request entity too large" {
+		t.Fatal(msg)
+	}
+}
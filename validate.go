@@ -0,0 +1,263 @@
+package web
+
+import (
+	"errors"
+	"github.com/elancom/go-util/lang"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 参数校验
+//
+//	type UserReq struct {
+//		Name  string `json:"name" validate:"required,max=32"`
+//		Age   int    `json:"age" validate:"min=1,max=150"`
+//		Email string `json:"email" validate:"required,email"`
+//		Sex   string `json:"sex" validate:"omitempty,oneof=m f"`
+//	}
+//
+// 规则: required 非零值; omitempty 零值时跳过后续规则; min/max 数值大小或字符串/切片长度; len 长度; email; oneof 空格分隔
+// 零值同样校验(如 min=1 拒绝0), 可选字段使用 omitempty 或指针(nil跳过)
+// 目标类型实现 Validator 时在标签校验后调用
+
+// CodeValidate 参数校验失败消息码, Data 为 []FieldError
+const CodeValidate = 422
+
+// Validator 自定义校验
+type Validator interface {
+	Validate() error
+}
+
+// FieldError 字段错误
+type FieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"msg"`
+}
+
+// FieldErrors 字段错误列表, 可由 Validator 返回
+type FieldErrors []FieldError
+
+func (f FieldErrors) Error() string {
+	s := make([]string, 0, len(f))
+	for _, it := range f {
+		s = append(s, it.Field+" "+it.Msg)
+	}
+	return strings.Join(s, "; ")
+}
+
+// 字段校验规则
+type fieldRules struct {
+	index []int
+	name  string
+	rules []rule
+}
+
+type rule struct {
+	name string
+	arg  string
+}
+
+// 类型校验规则缓存 reflect.Type => []fieldRules
+var validateRules sync.Map
+
+// Validate 校验(validate标签 + Validator), 失败返回 CodeValidate 消息
+func Validate(v any) error {
+	var errs FieldErrors
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		fields, err := validateRulesOf(rv.Type())
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			fv := rv.FieldByIndex(f.index)
+			for _, r := range f.rules {
+				if r.name == "omitempty" {
+					if isEmpty(fv) {
+						break
+					}
+					continue
+				}
+				if msg := checkRule(fv, r); msg != "" {
+					errs = append(errs, FieldError{Field: f.name, Msg: msg})
+					break
+				}
+			}
+		}
+	}
+
+	if validator, ok := v.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			var fe FieldErrors
+			if errors.As(err, &fe) {
+				errs = append(errs, fe...)
+			} else {
+				errs = append(errs, FieldError{Msg: err.Error()})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return lang.NewMsg(CodeValidate, "validate err", []FieldError(errs))
+	}
+	return nil
+}
+
+// 是否校验错误
+func isValidateErr(err error) bool {
+	msg, ok := err.(*lang.Msg)
+	return ok && msg.Code == CodeValidate
+}
+
+func validateRulesOf(t reflect.Type) ([]fieldRules, error) {
+	if fields, ok := validateRules.Load(t); ok {
+		return fields.([]fieldRules), nil
+	}
+	fields, err := parseValidateRules(t, nil)
+	if err != nil {
+		return nil, err
+	}
+	validateRules.Store(t, fields)
+	return fields, nil
+}
+
+// 解析校验规则, 嵌入结构体展开(同结构体绑定)
+func parseValidateRules(t reflect.Type, parent []int) ([]fieldRules, error) {
+	fields := make([]fieldRules, 0)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			sub, err := parseValidateRules(sf.Type, index)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, sub...)
+			continue
+		}
+		if tag == "" || tag == "-" {
+			continue
+		}
+		f := fieldRules{index: index, name: fieldName(sf)}
+		for _, it := range strings.Split(tag, ",") {
+			it = strings.TrimSpace(it)
+			if it == "" {
+				continue
+			}
+			r := rule{name: it}
+			if i := strings.IndexByte(it, '='); i > 0 {
+				r.name, r.arg = it[:i], it[i+1:]
+			}
+			switch r.name {
+			case "required", "omitempty", "email", "oneof":
+			case "min", "max", "len":
+				if _, err := strconv.ParseFloat(r.arg, 64); err != nil {
+					return nil, errors.New("validate rule err: " + sf.Name + " " + it)
+				}
+			default:
+				return nil, errors.New("validate rule not support: " + sf.Name + " " + it)
+			}
+			f.rules = append(f.rules, r)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// 字段名: json/绑定标签名优先
+func fieldName(sf reflect.StructField) string {
+	for _, key := range append([]string{"json"}, bindSources...) {
+		if tag := strings.Split(sf.Tag.Get(key), ",")[0]; tag != "" && tag != "-" {
+			return tag
+		}
+	}
+	return sf.Name
+}
+
+func checkRule(v reflect.Value, r rule) string {
+	if r.name == "required" {
+		if v.IsZero() {
+			return "required"
+		}
+		return ""
+	}
+
+	// nil指针只校验required
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max", "len":
+		limit, _ := strconv.ParseFloat(r.arg, 64)
+		n, isLen := measure(v)
+		switch {
+		case r.name == "min" && n < limit:
+			return lang.Ifs(isLen, "length min "+r.arg, "min "+r.arg)
+		case r.name == "max" && n > limit:
+			return lang.Ifs(isLen, "length max "+r.arg, "max "+r.arg)
+		case r.name == "len" && n != limit:
+			return "length must be " + r.arg
+		}
+	case "email":
+		if v.Kind() == reflect.String {
+			if _, err := mail.ParseAddress(v.String()); err != nil {
+				return "invalid email"
+			}
+		}
+	case "oneof":
+		s := ""
+		switch v.Kind() {
+		case reflect.String:
+			s = v.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s = strconv.FormatInt(v.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s = strconv.FormatUint(v.Uint(), 10)
+		}
+		for _, it := range strings.Fields(r.arg) {
+			if it == s {
+				return ""
+			}
+		}
+		return "must be one of " + r.arg
+	}
+	return ""
+}
+
+// 是否零值(含nil指针及指向零值的指针)
+func isEmpty(v reflect.Value) bool {
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.IsZero()
+}
+
+// 数值大小或长度
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+	return 0, false
+}